@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
@@ -40,7 +41,54 @@ func (a *Authenticator) GenerateJWT(user *users.User) (string, error) {
 	return token.SignedString([]byte(a.Secret))
 }
 
+// streamAudience marks tokens that can only open event streams.
+const streamAudience = "event-stream"
+
+// streamTokenTTL is how long a stream token can be used. Stream tokens go in
+// URLs so they are kept short lived.
+const streamTokenTTL = time.Minute
+
+// GenerateStreamToken makes a short lived token for opening event streams.
+// Browsers can't set headers on an EventSource so the token is passed in the
+// URL instead.
+func (a *Authenticator) GenerateStreamToken(user users.User) (string, error) {
+	var claims Claims
+	claims.Subject = user.ID
+	claims.Username = user.Username
+	claims.Roles = user.Roles
+	claims.Audience = jwt.ClaimStrings{streamAudience}
+	claims.IssuedAt = jwt.NewNumericDate(time.Now())
+	claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(streamTokenTTL))
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(a.Secret))
+}
+
+// ValidateToken checks a token from GenerateJWT. Stream tokens are rejected.
 func (a *Authenticator) ValidateToken(t string) (users.User, error) {
+	claims, err := a.parse(t)
+	if err != nil {
+		return users.User{}, err
+	}
+
+	if len(claims.Audience) > 0 {
+		return users.User{}, fmt.Errorf("token is for %v", claims.Audience)
+	}
+
+	return claims.user(), nil
+}
+
+// ValidateStreamToken checks a token from GenerateStreamToken.
+func (a *Authenticator) ValidateStreamToken(t string) (users.User, error) {
+	claims, err := a.parse(t, jwt.WithAudience(streamAudience))
+	if err != nil {
+		return users.User{}, err
+	}
+
+	return claims.user(), nil
+}
+
+func (a *Authenticator) parse(t string, opts ...jwt.ParserOption) (Claims, error) {
 
 	var claims Claims
 
@@ -50,20 +98,24 @@ func (a *Authenticator) ValidateToken(t string) (users.User, error) {
 		}
 
 		return []byte(a.Secret), nil
-	})
+	}, opts...)
 	if err != nil {
-		return users.User{}, err
+		return Claims{}, err
 	}
 
 	if !token.Valid {
-		return users.User{}, err
+		return Claims{}, errors.New("invalid token")
 	}
 
+	return claims, nil
+}
+
+func (c Claims) user() users.User {
 	return users.User{
-		ID:       claims.Subject,
-		Username: claims.Username,
-		Roles:    claims.Roles,
-	}, nil
+		ID:       c.Subject,
+		Username: c.Username,
+		Roles:    c.Roles,
+	}
 }
 
 func (a *Authenticator) Middleware() func(http.HandlerFunc) http.HandlerFunc {
@@ -91,3 +143,33 @@ func (a *Authenticator) Middleware() func(http.HandlerFunc) http.HandlerFunc {
 		})
 	}
 }
+
+// StreamMiddleware authenticates requests for event streams. A stream token
+// may be passed in the token query parameter for clients like EventSource
+// that can't set headers. Requests without one need the usual header.
+func (a *Authenticator) StreamMiddleware() func(http.HandlerFunc) http.HandlerFunc {
+	headerAuth := a.Middleware()
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+
+			t := r.URL.Query().Get("token")
+			if t == "" {
+				headerAuth(next)(w, r)
+				return
+			}
+
+			user, err := a.ValidateStreamToken(t)
+			if err != nil {
+				slog.WarnContext(ctx, "invalid stream authentication", "error", err)
+				http.Error(w, "invalid authentication", http.StatusUnauthorized)
+				return
+			}
+
+			ctx = StoreUser(ctx, user)
+			r = r.WithContext(ctx)
+
+			next(w, r)
+		})
+	}
+}
@@ -0,0 +1,331 @@
+package database
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+const (
+	markerUp   = "-- +migrate Up"
+	markerDown = "-- +migrate Down"
+)
+
+// migrationLock is the key of the advisory lock held while migrating so two
+// servers starting together don't both apply the same migrations.
+const migrationLock = 7349812306
+
+// initialTable is created by the first migration. Databases set up by hand
+// before migrations were tracked have it without any record of migration 1.
+const initialTable = "users"
+
+// Migration is one file from the migrations directory. Files are named like
+// "01-initial-setup.sql" where the leading number is the version.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus reports whether a migration has been applied.
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// Migrations parses the embedded migration files in version order.
+func Migrations() ([]Migration, error) {
+	files, err := fs.Glob(migrationFiles, "migrations/*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := make([]Migration, 0, len(files))
+	for _, file := range files {
+		b, err := migrationFiles.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		m, err := parseMigration(path.Base(file), string(b))
+		if err != nil {
+			return nil, err
+		}
+		migrations = append(migrations, m)
+	}
+
+	slices.SortFunc(migrations, func(a, b Migration) int {
+		return a.Version - b.Version
+	})
+
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].Version == migrations[i-1].Version {
+			return nil, fmt.Errorf("migrations %q and %q have the same version", migrations[i-1].Name, migrations[i].Name)
+		}
+	}
+
+	return migrations, nil
+}
+
+func parseMigration(filename, contents string) (Migration, error) {
+	base := strings.TrimSuffix(filename, ".sql")
+	num, name, _ := strings.Cut(base, "-")
+
+	version, err := strconv.Atoi(num)
+	if err != nil {
+		return Migration{}, fmt.Errorf("migration %q does not start with a version number", filename)
+	}
+
+	up, down, ok := strings.Cut(contents, markerDown)
+	if !ok {
+		return Migration{}, fmt.Errorf("migration %q has no %q section", filename, markerDown)
+	}
+	_, up, ok = strings.Cut(up, markerUp)
+	if !ok {
+		return Migration{}, fmt.Errorf("migration %q has no %q section", filename, markerUp)
+	}
+
+	return Migration{
+		Version: version,
+		Name:    name,
+		Up:      strings.TrimSpace(up),
+		Down:    strings.TrimSpace(down),
+	}, nil
+}
+
+// MigrateUp applies every migration that hasn't been applied yet. It returns
+// the migrations that were applied.
+//
+// A database created by hand before migrations were tracked is adopted by
+// marking the first migration as applied. Use Baseline first for a database
+// that was migrated further by hand.
+func MigrateUp(ctx context.Context, db *sqlx.DB) ([]Migration, error) {
+	var applied []Migration
+	err := withLock(ctx, db, func() error {
+		if err := adopt(ctx, db); err != nil {
+			return fmt.Errorf("adopting existing database: %w", err)
+		}
+
+		status, err := GetMigrationStatus(ctx, db)
+		if err != nil {
+			return err
+		}
+
+		for _, st := range status {
+			if st.AppliedAt != nil {
+				continue
+			}
+
+			err := inTx(ctx, db, func(tx *sqlx.Tx) error {
+				if _, err := tx.ExecContext(ctx, st.Up); err != nil {
+					return err
+				}
+				return markApplied(ctx, tx, st.Migration)
+			})
+			if err != nil {
+				return fmt.Errorf("applying migration %d %s: %w", st.Version, st.Name, err)
+			}
+			applied = append(applied, st.Migration)
+		}
+		return nil
+	})
+
+	return applied, err
+}
+
+// Baseline records every migration up to and including version as applied
+// without running it. It is for databases that were migrated by hand. It
+// returns the migrations that were marked.
+func Baseline(ctx context.Context, db *sqlx.DB, version int) ([]Migration, error) {
+	var marked []Migration
+	err := withLock(ctx, db, func() error {
+		status, err := GetMigrationStatus(ctx, db)
+		if err != nil {
+			return err
+		}
+
+		if !slices.ContainsFunc(status, func(st MigrationStatus) bool { return st.Version == version }) {
+			return fmt.Errorf("unknown migration version %d", version)
+		}
+
+		return inTx(ctx, db, func(tx *sqlx.Tx) error {
+			for _, st := range status {
+				if st.Version > version || st.AppliedAt != nil {
+					continue
+				}
+				if err := markApplied(ctx, tx, st.Migration); err != nil {
+					return err
+				}
+				marked = append(marked, st.Migration)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return marked, nil
+}
+
+// adopt marks the first migration as applied if nothing has been recorded but
+// the tables it creates already exist.
+func adopt(ctx context.Context, db *sqlx.DB) error {
+	status, err := GetMigrationStatus(ctx, db)
+	if err != nil {
+		return err
+	}
+	if len(status) == 0 || slices.ContainsFunc(status, func(st MigrationStatus) bool { return st.AppliedAt != nil }) {
+		return nil
+	}
+
+	const q = `SELECT to_regclass($1) IS NOT NULL`
+
+	var exists bool
+	if err := db.GetContext(ctx, &exists, q, initialTable); err != nil {
+		return err
+	}
+	if !exists {
+		return nil
+	}
+
+	return inTx(ctx, db, func(tx *sqlx.Tx) error {
+		return markApplied(ctx, tx, status[0].Migration)
+	})
+}
+
+func markApplied(ctx context.Context, tx *sqlx.Tx, m Migration) error {
+	const q = `
+	INSERT INTO schema_migrations (version, name, applied_at)
+	VALUES ($1, $2, $3)`
+
+	_, err := tx.ExecContext(ctx, q, m.Version, m.Name, time.Now())
+	return err
+}
+
+// MigrateDown rolls back the most recently applied migration. It returns
+// false if there was nothing to roll back.
+func MigrateDown(ctx context.Context, db *sqlx.DB) (Migration, bool, error) {
+	var (
+		rolledBack Migration
+		ok         bool
+	)
+	err := withLock(ctx, db, func() error {
+		status, err := GetMigrationStatus(ctx, db)
+		if err != nil {
+			return err
+		}
+
+		for i := len(status) - 1; i >= 0; i-- {
+			st := status[i]
+			if st.AppliedAt == nil {
+				continue
+			}
+
+			const q = `DELETE FROM schema_migrations WHERE version = $1`
+
+			err := inTx(ctx, db, func(tx *sqlx.Tx) error {
+				if _, err := tx.ExecContext(ctx, st.Down); err != nil {
+					return err
+				}
+				_, err := tx.ExecContext(ctx, q, st.Version)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("rolling back migration %d %s: %w", st.Version, st.Name, err)
+			}
+			rolledBack, ok = st.Migration, true
+			return nil
+		}
+		return nil
+	})
+	if err != nil {
+		return Migration{}, false, err
+	}
+
+	return rolledBack, ok, nil
+}
+
+// GetMigrationStatus lists every known migration and when it was applied.
+func GetMigrationStatus(ctx context.Context, db *sqlx.DB) ([]MigrationStatus, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	const create = `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TIMESTAMP NOT NULL
+	)`
+
+	if _, err := db.ExecContext(ctx, create); err != nil {
+		return nil, err
+	}
+
+	const q = `SELECT version, applied_at FROM schema_migrations`
+
+	var rows []struct {
+		Version   int       `db:"version"`
+		AppliedAt time.Time `db:"applied_at"`
+	}
+	if err := db.SelectContext(ctx, &rows, q); err != nil {
+		return nil, err
+	}
+
+	status := make([]MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		st := MigrationStatus{Migration: m}
+		for _, row := range rows {
+			if row.Version == m.Version {
+				st.AppliedAt = &row.AppliedAt
+			}
+		}
+		status = append(status, st)
+	}
+
+	return status, nil
+}
+
+// withLock runs fn while holding the migration lock. The lock belongs to a
+// session so it is taken on a connection of its own and released with it.
+func withLock(ctx context.Context, db *sqlx.DB, fn func() error) error {
+	conn, err := db.Connx(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLock); err != nil {
+		return fmt.Errorf("could not lock migrations: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLock)
+
+	return fn()
+}
+
+func inTx(ctx context.Context, db *sqlx.DB, fn func(tx *sqlx.Tx) error) error {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
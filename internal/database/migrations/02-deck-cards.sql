@@ -0,0 +1,30 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+
+CREATE TABLE deck_cards (
+  id TEXT PRIMARY KEY,
+  deck_id TEXT NOT NULL REFERENCES decks(id) ON DELETE CASCADE,
+  board TEXT NOT NULL,
+  quantity INTEGER NOT NULL,
+  finish TEXT NOT NULL DEFAULT '',
+  is_proxy BOOLEAN NOT NULL DEFAULT FALSE,
+  scryfall_id TEXT NOT NULL,
+  name TEXT NOT NULL,
+  set_code TEXT NOT NULL DEFAULT '',
+  collector_number TEXT NOT NULL DEFAULT '',
+  rarity TEXT NOT NULL DEFAULT '',
+  mana_cost TEXT NOT NULL DEFAULT '',
+  cmc DOUBLE PRECISION NOT NULL DEFAULT 0,
+  type_line TEXT NOT NULL DEFAULT '',
+  oracle_text TEXT NOT NULL DEFAULT '',
+  colors TEXT[] NOT NULL DEFAULT '{}',
+  color_identity TEXT[] NOT NULL DEFAULT '{}'
+);
+
+CREATE INDEX deck_cards_deck_id_idx ON deck_cards (deck_id);
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+
+DROP TABLE deck_cards;
@@ -0,0 +1,27 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+
+-- Put existing identities in WUBRG order so they can be compared and grouped.
+UPDATE decks SET color_identity = ARRAY(
+  SELECT c
+  FROM unnest(color_identity) AS c
+  ORDER BY array_position(ARRAY['w', 'u', 'b', 'r', 'g'], c)
+);
+
+UPDATE deck_cards SET
+  colors = ARRAY(
+    SELECT c
+    FROM unnest(colors) AS c
+    ORDER BY array_position(ARRAY['w', 'u', 'b', 'r', 'g'], c)
+  ),
+  color_identity = ARRAY(
+    SELECT c
+    FROM unnest(color_identity) AS c
+    ORDER BY array_position(ARRAY['w', 'u', 'b', 'r', 'g'], c)
+  );
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+
+-- Nothing to undo, the original order was not meaningful.
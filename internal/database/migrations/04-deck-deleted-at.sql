@@ -0,0 +1,10 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+
+ALTER TABLE decks ADD COLUMN deleted_at TIMESTAMP DEFAULT NULL;
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+
+ALTER TABLE decks DROP COLUMN deleted_at;
@@ -0,0 +1,28 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+
+CREATE TABLE refresh_jobs (
+  id TEXT PRIMARY KEY,
+  account_id TEXT NOT NULL REFERENCES user_accounts(id) ON DELETE CASCADE,
+  user_id TEXT NOT NULL REFERENCES users(id),
+  status TEXT NOT NULL,
+  attempts INTEGER NOT NULL DEFAULT 0,
+  max_attempts INTEGER NOT NULL,
+  last_error TEXT NOT NULL DEFAULT '',
+  run_at TIMESTAMP NOT NULL,
+  created_at TIMESTAMP NOT NULL,
+  updated_at TIMESTAMP NOT NULL
+);
+
+-- Only one job per account can be waiting or running at a time.
+CREATE UNIQUE INDEX refresh_jobs_active_account_idx
+  ON refresh_jobs (account_id)
+  WHERE status IN ('queued', 'running');
+
+CREATE INDEX refresh_jobs_run_at_idx ON refresh_jobs (run_at);
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+
+DROP TABLE refresh_jobs;
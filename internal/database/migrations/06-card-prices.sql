@@ -0,0 +1,21 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+
+-- One price per card, vendor and finish per day. Vendors are the price keys
+-- Moxfield reports such as usd, eur, tix or ck.
+CREATE TABLE card_prices (
+  scryfall_id TEXT NOT NULL,
+  vendor TEXT NOT NULL,
+  finish TEXT NOT NULL,
+  price NUMERIC NOT NULL,
+  captured_on DATE NOT NULL,
+  PRIMARY KEY (scryfall_id, vendor, finish, captured_on)
+);
+
+CREATE INDEX card_prices_vendor_idx ON card_prices (vendor, scryfall_id);
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+
+DROP TABLE card_prices;
@@ -0,0 +1,25 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+
+CREATE TABLE games (
+  id TEXT PRIMARY KEY,
+  user_id TEXT NOT NULL REFERENCES users(id),
+  deck_id TEXT NOT NULL REFERENCES decks(id) ON DELETE CASCADE,
+  played_at TIMESTAMP NOT NULL,
+  format TEXT NOT NULL,
+  opponents JSONB NOT NULL DEFAULT '[]',
+  result TEXT NOT NULL,
+  turns INTEGER NOT NULL DEFAULT 0,
+  notes TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMP NOT NULL,
+  updated_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX games_user_played_at_idx ON games (user_id, played_at);
+CREATE INDEX games_deck_idx ON games (deck_id);
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+
+DROP TABLE games;
@@ -0,0 +1,63 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+
+-- Every player in a game including the user. Zero in position,
+-- eliminated_order or eliminated_turn means it wasn't recorded.
+CREATE TABLE game_seats (
+  id TEXT PRIMARY KEY,
+  game_id TEXT NOT NULL REFERENCES games(id) ON DELETE CASCADE,
+  position INTEGER NOT NULL DEFAULT 0,
+  player TEXT NOT NULL DEFAULT '',
+  is_user BOOLEAN NOT NULL DEFAULT FALSE,
+  deck_id TEXT NOT NULL DEFAULT '',
+  deck TEXT NOT NULL DEFAULT '',
+  commander TEXT NOT NULL DEFAULT '',
+  won BOOLEAN NOT NULL DEFAULT FALSE,
+  eliminated_order INTEGER NOT NULL DEFAULT 0,
+  eliminated_turn INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE INDEX game_seats_game_idx ON game_seats (game_id);
+
+ALTER TABLE games ADD COLUMN end_reason TEXT NOT NULL DEFAULT '';
+
+-- Games logged before seats become the user's seat plus one seat per opponent
+-- with no turn order.
+INSERT INTO game_seats (id, game_id, is_user, deck_id, won)
+SELECT id || '-0', id, TRUE, deck_id, result = 'win'
+FROM games;
+
+INSERT INTO game_seats (id, game_id, player, deck_id, deck, won)
+SELECT
+  g.id || '-' || o.n,
+  g.id,
+  COALESCE(o.opponent->>'name', ''),
+  COALESCE(o.opponent->>'deck_id', ''),
+  COALESCE(o.opponent->>'deck', ''),
+  COALESCE((o.opponent->>'won')::BOOLEAN, FALSE)
+FROM games g
+CROSS JOIN LATERAL jsonb_array_elements(g.opponents) WITH ORDINALITY AS o(opponent, n);
+
+ALTER TABLE games DROP COLUMN opponents;
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+
+ALTER TABLE games ADD COLUMN opponents JSONB NOT NULL DEFAULT '[]';
+
+UPDATE games g SET opponents = COALESCE((
+  SELECT jsonb_agg(jsonb_strip_nulls(jsonb_build_object(
+    'name', s.player,
+    'deck', NULLIF(s.deck, ''),
+    'deck_id', NULLIF(s.deck_id, ''),
+    'won', CASE WHEN s.won THEN TRUE END
+  )) ORDER BY s.position, s.id)
+  FROM game_seats s
+  WHERE s.game_id = g.id
+    AND NOT s.is_user
+), '[]');
+
+ALTER TABLE games DROP COLUMN end_reason;
+
+DROP TABLE game_seats;
@@ -0,0 +1,32 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+
+CREATE TABLE deck_ratings (
+  deck_id TEXT PRIMARY KEY REFERENCES decks(id) ON DELETE CASCADE,
+  user_id TEXT NOT NULL REFERENCES users(id),
+  rating DOUBLE PRECISION NOT NULL,
+  games INTEGER NOT NULL,
+  updated_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX deck_ratings_user_idx ON deck_ratings (user_id, rating);
+
+-- How each game changed the rating of each deck that played in it.
+CREATE TABLE rating_history (
+  deck_id TEXT NOT NULL REFERENCES decks(id) ON DELETE CASCADE,
+  game_id TEXT NOT NULL REFERENCES games(id) ON DELETE CASCADE,
+  user_id TEXT NOT NULL REFERENCES users(id),
+  played_at TIMESTAMP NOT NULL,
+  rating_before DOUBLE PRECISION NOT NULL,
+  rating_after DOUBLE PRECISION NOT NULL,
+  PRIMARY KEY (deck_id, game_id)
+);
+
+CREATE INDEX rating_history_user_idx ON rating_history (user_id);
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+
+DROP TABLE rating_history;
+DROP TABLE deck_ratings;
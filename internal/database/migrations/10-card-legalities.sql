@@ -0,0 +1,10 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+
+ALTER TABLE deck_cards ADD COLUMN legalities JSONB NOT NULL DEFAULT '{}';
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+
+ALTER TABLE deck_cards DROP COLUMN legalities;
@@ -0,0 +1,45 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+
+ALTER TABLE decks ADD COLUMN version INTEGER NOT NULL DEFAULT 0;
+
+-- A snapshot of a deck's cards is kept for every version of the deck seen on
+-- its service. The service's version and update time identify a version.
+CREATE TABLE deck_versions (
+  id TEXT PRIMARY KEY,
+  deck_id TEXT NOT NULL REFERENCES decks(id) ON DELETE CASCADE,
+  version INTEGER NOT NULL DEFAULT 0,
+  updated_at TIMESTAMP NOT NULL,
+  captured_at TIMESTAMP NOT NULL,
+  cards JSONB NOT NULL DEFAULT '[]',
+  UNIQUE (deck_id, version, updated_at)
+);
+
+-- Existing decks start their history with the cards they have now. Decks
+-- without cards take their first snapshot when they are next refreshed.
+INSERT INTO deck_versions (id, deck_id, updated_at, captured_at, cards)
+SELECT
+  d.id || '-0',
+  d.id,
+  COALESCE(d.updated_at, NOW()),
+  COALESCE(d.refreshed_at, NOW()),
+  COALESCE((
+    SELECT jsonb_agg(jsonb_build_object(
+      'board', c.board,
+      'name', c.name,
+      'scryfall_id', c.scryfall_id,
+      'quantity', c.quantity
+    ) ORDER BY c.board, c.name)
+    FROM deck_cards c
+    WHERE c.deck_id = d.id
+  ), '[]')
+FROM decks d
+WHERE EXISTS (SELECT 1 FROM deck_cards c WHERE c.deck_id = d.id);
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+
+DROP TABLE deck_versions;
+
+ALTER TABLE decks DROP COLUMN version;
@@ -0,0 +1,29 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+
+-- Decks belong to the account they were synced from so a user can have more
+-- than one account on a service.
+ALTER TABLE decks ADD COLUMN account_id TEXT DEFAULT NULL REFERENCES user_accounts(id) ON DELETE SET NULL;
+
+CREATE INDEX decks_account_idx ON decks (account_id);
+
+-- A deck must be from the user's only account on its service. Decks of users
+-- with several accounts on a service are left for the next refresh of the
+-- account that finds them.
+UPDATE decks d SET
+  account_id = a.id
+FROM user_accounts a
+WHERE a.user_id = d.user_id
+  AND a.service = d.service
+  AND (
+    SELECT COUNT(*)
+    FROM user_accounts o
+    WHERE o.user_id = d.user_id
+      AND o.service = d.service
+  ) = 1;
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+
+ALTER TABLE decks DROP COLUMN account_id;
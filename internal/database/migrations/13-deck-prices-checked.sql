@@ -0,0 +1,13 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+
+-- When prices were last fetched for a deck. Decks without prices, like those
+-- from services that don't report any, would otherwise be fetched again on
+-- every refresh.
+ALTER TABLE decks ADD COLUMN prices_checked_at TIMESTAMP DEFAULT NULL;
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+
+ALTER TABLE decks DROP COLUMN prices_checked_at;
@@ -0,0 +1,16 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+
+-- Games are history that outlives their deck. Decks are soft deleted so a
+-- deck with games should never be removed and the database refuses to.
+ALTER TABLE games DROP CONSTRAINT games_deck_id_fkey;
+ALTER TABLE games ADD CONSTRAINT games_deck_id_fkey
+  FOREIGN KEY (deck_id) REFERENCES decks(id) ON DELETE RESTRICT;
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+
+ALTER TABLE games DROP CONSTRAINT games_deck_id_fkey;
+ALTER TABLE games ADD CONSTRAINT games_deck_id_fkey
+  FOREIGN KEY (deck_id) REFERENCES decks(id) ON DELETE CASCADE;
@@ -0,0 +1,21 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+
+-- The latest legalities of every printing in a deck. Cards are fetched from
+-- Scryfall again once they are a day old so bans show up in decks that never
+-- change. Cards without a row, including every card stored before legalities
+-- were, are fetched first. Until then the legalities stored with the deck's
+-- cards are used.
+CREATE TABLE card_legalities (
+  scryfall_id TEXT PRIMARY KEY,
+  legalities JSONB NOT NULL DEFAULT '{}',
+  checked_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX card_legalities_checked_at_idx ON card_legalities (checked_at);
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+
+DROP TABLE card_legalities;
@@ -0,0 +1,12 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+
+-- When a deck's version was last compared to its service. Services don't list
+-- versions so checking one means fetching the deck's details.
+ALTER TABLE decks ADD COLUMN versions_checked_at TIMESTAMP DEFAULT NULL;
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+
+ALTER TABLE decks DROP COLUMN versions_checked_at;
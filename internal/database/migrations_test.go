@@ -0,0 +1,60 @@
+package database
+
+import (
+	"testing"
+)
+
+func TestMigrations(t *testing.T) {
+	migrations, err := Migrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(migrations) == 0 {
+		t.Fatal("should find embedded migrations")
+	}
+
+	for i, m := range migrations {
+		if got, want := m.Version, i+1; got != want {
+			t.Errorf("migration %q should be version %d but was %d", m.Name, want, got)
+		}
+		if m.Up == "" {
+			t.Errorf("migration %q has an empty Up section", m.Name)
+		}
+	}
+
+	if got, want := migrations[0].Name, "initial-setup"; got != want {
+		t.Errorf("first migration should be %q but was %q", want, got)
+	}
+}
+
+func TestParseMigration(t *testing.T) {
+	const contents = `-- +migrate Up
+CREATE TABLE things (id TEXT);
+
+-- +migrate Down
+DROP TABLE things;
+`
+
+	m, err := parseMigration("12-add-things.sql", contents)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := Migration{
+		Version: 12,
+		Name:    "add-things",
+		Up:      "CREATE TABLE things (id TEXT);",
+		Down:    "DROP TABLE things;",
+	}
+	if m != want {
+		t.Errorf("parsed migration should be %+v but was %+v", want, m)
+	}
+
+	if _, err := parseMigration("add-things.sql", contents); err == nil {
+		t.Error("migration without a version should fail")
+	}
+	if _, err := parseMigration("13-no-down.sql", "-- +migrate Up\nSELECT 1;"); err == nil {
+		t.Error("migration without a down section should fail")
+	}
+}
@@ -0,0 +1,132 @@
+package magic
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/jcbwlkr/deck-stats/internal/domains/users"
+)
+
+// ArchetypeStats describes the user's decks under one archetype. WinRate is
+// only set if games have been logged with those decks.
+type ArchetypeStats struct {
+	Name       string         `json:"name"`
+	Decks      int            `json:"decks"`
+	Identities map[string]int `json:"identities"`
+	WinRate    *WinRate       `json:"win_rate,omitempty"`
+}
+
+// archetypeDecks is how many decks of one color identity are in an
+// archetype.
+type archetypeDecks struct {
+	Name          string        `db:"name"`
+	ColorIdentity ColorIdentity `db:"color_identity"`
+	Decks         int           `db:"decks"`
+}
+
+// archetypeGames is the game results of every deck in an archetype.
+type archetypeGames struct {
+	Name       string `db:"name"`
+	Games      int    `db:"games"`
+	Wins       int    `db:"wins"`
+	Losses     int    `db:"losses"`
+	Draws      int    `db:"draws"`
+	Turns      int    `db:"turns"`
+	TurnsGames int    `db:"turns_games"`
+}
+
+// GetArchetypeStats reports how the user's decks spread over the archetypes
+// they were tagged with on Moxfield. The counting is done in the database by
+// unnesting the archetypes of each deck.
+func (s *Service) GetArchetypeStats(ctx context.Context, user users.User) ([]ArchetypeStats, error) {
+
+	const decksQ = `
+	SELECT
+		a->>'name' AS name,
+		d.color_identity,
+		COUNT(*) AS decks
+	FROM decks d
+	CROSS JOIN LATERAL jsonb_array_elements(d.archetypes) a
+	WHERE d.user_id = $1
+		AND d.deleted_at IS NULL
+	GROUP BY a->>'name', d.color_identity`
+
+	decks := []archetypeDecks{}
+	if err := s.db.SelectContext(ctx, &decks, decksQ, user.ID); err != nil {
+		return nil, err
+	}
+
+	const gamesQ = `
+	SELECT
+		a->>'name' AS name,
+		COUNT(*) AS games,
+		COUNT(*) FILTER (WHERE g.result = 'win') AS wins,
+		COUNT(*) FILTER (WHERE g.result = 'loss') AS losses,
+		COUNT(*) FILTER (WHERE g.result = 'draw') AS draws,
+		COALESCE(SUM(g.turns), 0) AS turns,
+		COUNT(*) FILTER (WHERE g.turns > 0) AS turns_games
+	FROM games g
+	JOIN decks d ON d.id = g.deck_id
+	CROSS JOIN LATERAL jsonb_array_elements(d.archetypes) a
+	WHERE g.user_id = $1
+		AND d.deleted_at IS NULL
+	GROUP BY a->>'name'`
+
+	games := []archetypeGames{}
+	if err := s.db.SelectContext(ctx, &games, gamesQ, user.ID); err != nil {
+		return nil, err
+	}
+
+	return buildArchetypeStats(decks, games), nil
+}
+
+// buildArchetypeStats combines the deck counts and game results and sorts the
+// archetypes with the most decks first.
+func buildArchetypeStats(decks []archetypeDecks, games []archetypeGames) []ArchetypeStats {
+	byName := map[string]*ArchetypeStats{}
+
+	for _, d := range decks {
+		a, ok := byName[d.Name]
+		if !ok {
+			a = &ArchetypeStats{Name: d.Name, Identities: map[string]int{}}
+			byName[d.Name] = a
+		}
+		a.Decks += d.Decks
+		if name := d.ColorIdentity.Name(); name != "" {
+			a.Identities[name] += d.Decks
+		}
+	}
+
+	for _, g := range games {
+		a, ok := byName[g.Name]
+		if !ok {
+			continue
+		}
+		a.WinRate = &WinRate{
+			Key:        g.Name,
+			Name:       g.Name,
+			Games:      g.Games,
+			Wins:       g.Wins,
+			Losses:     g.Losses,
+			Draws:      g.Draws,
+			turns:      g.Turns,
+			turnsGames: g.TurnsGames,
+		}
+		a.WinRate.finish()
+	}
+
+	stats := make([]ArchetypeStats, 0, len(byName))
+	for _, a := range byName {
+		stats = append(stats, *a)
+	}
+	slices.SortFunc(stats, func(a, b ArchetypeStats) int {
+		if c := cmp.Compare(b.Decks, a.Decks); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return stats
+}
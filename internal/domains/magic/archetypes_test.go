@@ -0,0 +1,49 @@
+package magic
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestBuildArchetypeStats(t *testing.T) {
+	decks := []archetypeDecks{
+		{Name: "Tokens", ColorIdentity: Selesnya, Decks: 2},
+		{Name: "Tokens", ColorIdentity: MonoWhite, Decks: 1},
+		{Name: "Spellslinger", ColorIdentity: Izzet, Decks: 1},
+		{Name: "Aristocrats", ColorIdentity: Orzhov, Decks: 1},
+	}
+	games := []archetypeGames{
+		{Name: "Tokens", Games: 4, Wins: 3, Losses: 1, Turns: 30, TurnsGames: 3},
+
+		// Left out since no deck counted above has it.
+		{Name: "Stax", Games: 1, Wins: 1},
+	}
+
+	want := []ArchetypeStats{
+		{
+			Name:       "Tokens",
+			Decks:      3,
+			Identities: map[string]int{"Selesnya": 2, "Mono-White": 1},
+			WinRate: &WinRate{
+				Key:          "Tokens",
+				Name:         "Tokens",
+				Games:        4,
+				Wins:         3,
+				Losses:       1,
+				WinRate:      0.75,
+				Lower:        0.3006,
+				Upper:        0.9544,
+				AverageTurns: 10,
+			},
+		},
+		{Name: "Aristocrats", Decks: 1, Identities: map[string]int{"Orzhov": 1}},
+		{Name: "Spellslinger", Decks: 1, Identities: map[string]int{"Izzet": 1}},
+	}
+
+	got := buildArchetypeStats(decks, games)
+	if diff := cmp.Diff(want, got, cmpopts.IgnoreUnexported(WinRate{})); diff != "" {
+		t.Errorf("wrong archetype stats:\n%s", diff)
+	}
+}
@@ -2,6 +2,9 @@ package magic
 
 import (
 	"database/sql/driver"
+	"encoding/json"
+	"slices"
+	"strings"
 
 	"github.com/lib/pq"
 )
@@ -10,15 +13,19 @@ type Color string
 
 const (
 	White Color = "w"
-	Blue        = "u"
-	Black       = "b"
-	Red         = "r"
-	Green       = "g"
+	Blue  Color = "u"
+	Black Color = "b"
+	Red   Color = "r"
+	Green Color = "g"
 )
 
+// Colors lists every color in the canonical WUBRG order.
+var Colors = []Color{White, Blue, Black, Red, Green}
+
 type ColorIdentity []Color
 
 var (
+	Colorless     = ColorIdentity{}
 	MonoWhite     = ColorIdentity{White}
 	MonoBlue      = ColorIdentity{Blue}
 	MonoBlack     = ColorIdentity{Black}
@@ -52,6 +59,185 @@ var (
 	WUBRG         = ColorIdentity{White, Blue, Black, Red, Green}
 )
 
+// NamedIdentity pairs a color identity with the name players use for it.
+type NamedIdentity struct {
+	Name     string
+	Identity ColorIdentity
+}
+
+// NamedIdentities lists every possible color identity.
+var NamedIdentities = []NamedIdentity{
+	{"Colorless", Colorless},
+	{"Mono-White", MonoWhite},
+	{"Mono-Blue", MonoBlue},
+	{"Mono-Black", MonoBlack},
+	{"Mono-Red", MonoRed},
+	{"Mono-Green", MonoGreen},
+	{"Azorius", Azorius},
+	{"Orzhov", Orzhov},
+	{"Boros", Boros},
+	{"Selesnya", Selesnya},
+	{"Dimir", Dimir},
+	{"Simic", Simic},
+	{"Izzet", Izzet},
+	{"Rakdos", Rakdos},
+	{"Golgari", Golgari},
+	{"Gruul", Gruul},
+	{"Esper", Esper},
+	{"Bant", Bant},
+	{"Jeskai", Jeskai},
+	{"Mardu", Mardu},
+	{"Abzan", Abzan},
+	{"Naya", Naya},
+	{"Grixis", Grixis},
+	{"Sultai", Sultai},
+	{"Temur", Temur},
+	{"Jund", Jund},
+	{"Yore-Tiller", YoreTiller},
+	{"Witch-Maw", WitchMaw},
+	{"Ink-Treader", InkTreader},
+	{"Dune-Brood", DuneBlackrood},
+	{"Glint-Eye", GlintEye},
+	{"WUBRG", WUBRG},
+}
+
+// Normalize returns a copy of the identity with lower case colors in WUBRG
+// order. Duplicates and anything that isn't a color are dropped.
+func (id ColorIdentity) Normalize() ColorIdentity {
+	norm := make(ColorIdentity, 0, len(id))
+	for _, c := range Colors {
+		if slices.ContainsFunc(id, func(o Color) bool {
+			return Color(strings.ToLower(string(o))) == c
+		}) {
+			norm = append(norm, c)
+		}
+	}
+	return norm
+}
+
+// Equal reports whether both identities have the same colors in any order.
+func (id ColorIdentity) Equal(other ColorIdentity) bool {
+	return slices.Equal(id.Normalize(), other.Normalize())
+}
+
+// Lookup finds the named identity with the same colors as id. Identities
+// containing anything that isn't a color are not found.
+func (id ColorIdentity) Lookup() (NamedIdentity, bool) {
+	for _, c := range id {
+		if !slices.Contains(Colors, Color(strings.ToLower(string(c)))) {
+			return NamedIdentity{}, false
+		}
+	}
+	for _, named := range NamedIdentities {
+		if id.Equal(named.Identity) {
+			return named, true
+		}
+	}
+	return NamedIdentity{}, false
+}
+
+// Name returns the name of the identity such as "Izzet" regardless of the
+// order its colors are in. It returns "" for identities that aren't made of
+// known colors.
+func (id ColorIdentity) Name() string {
+	named, _ := id.Lookup()
+	return named.Name
+}
+
+// ParseColorIdentity accepts the name of an identity like "Izzet" or its
+// letters like "UR" in any order and case.
+func ParseColorIdentity(s string) (ColorIdentity, bool) {
+	for _, named := range NamedIdentities {
+		if strings.EqualFold(named.Name, s) {
+			return named.Identity, true
+		}
+	}
+
+	id := make(ColorIdentity, 0, len(s))
+	for _, r := range strings.ToLower(s) {
+		c := Color(r)
+		if !slices.Contains(Colors, c) {
+			return nil, false
+		}
+		id = append(id, c)
+	}
+	return id.Normalize(), true
+}
+
+// ColorReport counts how many decks are built in each color identity and
+// how many include each individual color.
+type ColorReport struct {
+	Format     string         `json:"format,omitempty"`
+	Decks      int            `json:"decks"`
+	Identities map[string]int `json:"identities"`
+	Colors     map[Color]int  `json:"colors"`
+}
+
+// NewColorReport tallies the identities. Every named identity and color is
+// present in the report even if no deck uses it.
+func NewColorReport(format string, identities []ColorIdentity) ColorReport {
+	report := ColorReport{
+		Format:     format,
+		Decks:      len(identities),
+		Identities: map[string]int{},
+		Colors:     map[Color]int{},
+	}
+	for _, named := range NamedIdentities {
+		report.Identities[named.Name] = 0
+	}
+	for _, c := range Colors {
+		report.Colors[c] = 0
+	}
+
+	for _, id := range identities {
+		if name := id.Name(); name != "" {
+			report.Identities[name]++
+		}
+		for _, c := range id.Normalize() {
+			report.Colors[c]++
+		}
+	}
+
+	return report
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// JSON Methods
+////////////////////////////////////////////////////////////////////////////////
+
+// IdentityReport is how a color identity is reported with both its letters
+// and its name. Identities are otherwise encoded as a plain array of letters.
+type IdentityReport struct {
+	Colors ColorIdentity `json:"colors"`
+	Name   string        `json:"name,omitempty"`
+}
+
+// Report pairs the identity's letters in WUBRG order with its name.
+func (id ColorIdentity) Report() IdentityReport {
+	norm := id.Normalize()
+	return IdentityReport{
+		Colors: norm,
+		Name:   norm.Name(),
+	}
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface. It accepts a plain
+// array of letters or the object form of an IdentityReport.
+func (id *ColorIdentity) UnmarshalJSON(b []byte) error {
+	var colors []Color
+	if err := json.Unmarshal(b, &colors); err != nil {
+		var v struct {
+			Colors []Color `json:"colors"`
+		}
+		if err := json.Unmarshal(b, &v); err != nil {
+			return err
+		}
+		colors = v.Colors
+	}
+	*id = ColorIdentity(colors).Normalize()
+	return nil
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 // DB Methods for Storing
 ////////////////////////////////////////////////////////////////////////////////
@@ -59,7 +245,7 @@ var (
 // Value implements the driver.Valuer interface
 func (id ColorIdentity) Value() (driver.Value, error) {
 	s := make([]string, 0, len(id))
-	for _, c := range id {
+	for _, c := range id.Normalize() {
 		s = append(s, string(c))
 	}
 	return pq.StringArray(s).Value()
@@ -76,6 +262,6 @@ func (id *ColorIdentity) Scan(src interface{}) error {
 	for _, c := range s {
 		tmp = append(tmp, Color(c))
 	}
-	*id = tmp
+	*id = tmp.Normalize()
 	return nil
 }
@@ -0,0 +1,144 @@
+package magic
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestNewColorReport(t *testing.T) {
+	identities := []ColorIdentity{
+		{Red, Blue},
+		Izzet,
+		{Black, Red, Blue},
+		Colorless,
+		WUBRG,
+	}
+
+	report := NewColorReport("commander", identities)
+
+	if got, want := report.Decks, 5; got != want {
+		t.Errorf("report should have %d decks but had %d", want, got)
+	}
+
+	wantIdentities := map[string]int{
+		"Izzet":     2,
+		"Grixis":    1,
+		"Colorless": 1,
+		"WUBRG":     1,
+		"Azorius":   0,
+	}
+	for name, want := range wantIdentities {
+		if got := report.Identities[name]; got != want {
+			t.Errorf("report should have %d %s decks but had %d", want, name, got)
+		}
+	}
+
+	wantColors := map[Color]int{
+		White: 1,
+		Blue:  4,
+		Black: 2,
+		Red:   4,
+		Green: 1,
+	}
+	for c, want := range wantColors {
+		if got := report.Colors[c]; got != want {
+			t.Errorf("report should have %d decks with %s but had %d", want, c, got)
+		}
+	}
+}
+
+func TestColorIdentityNormalize(t *testing.T) {
+	tests := []struct {
+		name  string
+		input ColorIdentity
+		want  ColorIdentity
+	}{
+		{"empty", nil, Colorless},
+		{"ordered", Izzet, Izzet},
+		{"reversed", ColorIdentity{Red, Blue}, Izzet},
+		{"upper case", ColorIdentity{"G", "R", "B"}, Jund},
+		{"duplicates", ColorIdentity{White, White, Green}, Selesnya},
+		{"unknown", ColorIdentity{"c", Black}, MonoBlack},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.input.Normalize()
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("normalized should be %v but was %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestColorIdentityLookup(t *testing.T) {
+	for _, named := range NamedIdentities {
+		reversed := slices.Clone(named.Identity)
+		slices.Reverse(reversed)
+
+		got, ok := reversed.Lookup()
+		if !ok {
+			t.Errorf("%v should resolve to %s", reversed, named.Name)
+			continue
+		}
+		if got.Name != named.Name {
+			t.Errorf("%v should resolve to %s but was %s", reversed, named.Name, got.Name)
+		}
+	}
+
+	if _, ok := (ColorIdentity{"x"}).Lookup(); ok {
+		t.Error("unknown colors should not resolve to a named identity")
+	}
+}
+
+func TestParseColorIdentity(t *testing.T) {
+	tests := []struct {
+		input string
+		want  ColorIdentity
+		ok    bool
+	}{
+		{"Izzet", Izzet, true},
+		{"witch-maw", WitchMaw, true},
+		{"RU", Izzet, true},
+		{"gwubr", WUBRG, true},
+		{"Colorless", Colorless, true},
+		{"XYZ", nil, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := ParseColorIdentity(tt.input)
+		if ok != tt.ok || !slices.Equal(got, tt.want) {
+			t.Errorf("parsing %q should give %v, %t but got %v, %t", tt.input, tt.want, tt.ok, got, ok)
+		}
+	}
+}
+
+func TestColorIdentityJSON(t *testing.T) {
+	b, err := json.Marshal(ColorIdentity{Red, Blue}.Report())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(b), `{"colors":["u","r"],"name":"Izzet"}`; got != want {
+		t.Errorf("report json should be %s but was %s", want, got)
+	}
+
+	// Identities on their own stay plain arrays so card colors are unchanged.
+	b, err = json.Marshal(ColorIdentity{Blue, Red})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(b), `["u","r"]`; got != want {
+		t.Errorf("json should be %s but was %s", want, got)
+	}
+
+	for _, input := range []string{`{"colors":["r","u"],"name":"Izzet"}`, `["r","u"]`} {
+		var id ColorIdentity
+		if err := json.Unmarshal([]byte(input), &id); err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(id, Izzet) {
+			t.Errorf("decoding %s should give %v but was %v", input, Izzet, id)
+		}
+	}
+}
@@ -0,0 +1,439 @@
+package magic
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Rules of Commander deck construction.
+const (
+	RuleDeckSize      = "deck_size"
+	RuleSingleton     = "singleton"
+	RuleColorIdentity = "color_identity"
+	RuleCommander     = "commander"
+	RuleCompanion     = "companion"
+)
+
+// commanderDeckSize is how many cards a Commander deck has, commanders
+// included.
+const commanderDeckSize = 100
+
+// commanderFormats are the formats built with Commander's construction rules.
+var commanderFormats = []string{"commander", "duel", "duelcommander", "predh"}
+
+// cardLimit matches oracle text that lifts the singleton rule for a card.
+var cardLimit = regexp.MustCompile(`A deck can have (?:any number of|up to (\w+)) cards named`)
+
+// numberWords spells out the limits cards print in their oracle text.
+var numberWords = map[string]int{"seven": 7, "nine": 9}
+
+// kaheeraTypes are the creature types Kaheera allows.
+var kaheeraTypes = []string{"Cat", "Elemental", "Nightmare", "Dinosaur", "Beast"}
+
+// permanentTypes are the card types that are permanents.
+var permanentTypes = []string{"Artifact", "Battle", "Creature", "Enchantment", "Land", "Planeswalker"}
+
+// CheckCommander checks the deck against Commander's construction rules: a
+// deck size of exactly 100, one copy of each card, every card within the
+// commanders' color identity, a legal pairing of commanders and the deck
+// building restriction of any companion.
+func CheckCommander(deck Deck) []Violation {
+	var (
+		violations []Violation
+		commanders []DeckCard
+		companions []DeckCard
+		cards      []DeckCard
+	)
+	for _, c := range deck.Cards {
+		switch {
+		case c.Board == BoardCompanions:
+			companions = append(companions, c)
+		case c.Board == BoardCommanders:
+			commanders = append(commanders, c)
+			cards = append(cards, c)
+		case c.InDeck():
+			cards = append(cards, c)
+		}
+	}
+
+	violations = append(violations, checkDeckSize(cards)...)
+	violations = append(violations, checkSingleton(cards)...)
+	violations = append(violations, checkCommanders(commanders)...)
+	violations = append(violations, checkColorIdentity(commanders, append(slices.Clone(cards), companions...))...)
+	violations = append(violations, checkCompanions(companions, cards)...)
+
+	return violations
+}
+
+func checkDeckSize(cards []DeckCard) []Violation {
+	var size int
+	for _, c := range cards {
+		size += c.Quantity
+	}
+	if size == commanderDeckSize {
+		return nil
+	}
+	return []Violation{{
+		Rule:    RuleDeckSize,
+		Message: fmt.Sprintf("deck has %d cards but must have exactly %d", size, commanderDeckSize),
+	}}
+}
+
+func checkSingleton(cards []DeckCard) []Violation {
+	var names []string
+	quantities := map[string]int{}
+	first := map[string]DeckCard{}
+	for _, c := range cards {
+		if _, ok := first[c.Name]; !ok {
+			names = append(names, c.Name)
+			first[c.Name] = c
+		}
+		quantities[c.Name] += c.Quantity
+	}
+
+	var violations []Violation
+	for _, name := range names {
+		c := first[name]
+		limit, ok := c.copyLimit()
+		if !ok || quantities[name] <= limit {
+			continue
+		}
+		violations = append(violations, Violation{
+			Rule:    RuleSingleton,
+			Card:    name,
+			Board:   c.Board,
+			Message: fmt.Sprintf("deck has %d copies of %s but may only have %d", quantities[name], name, limit),
+		})
+	}
+	return violations
+}
+
+// copyLimit is how many copies of the card a singleton deck may have. It is
+// false if the deck may have any number like basic lands and Relentless Rats.
+func (c DeckCard) copyLimit() (int, bool) {
+	if c.isBasic() {
+		return 0, false
+	}
+
+	m := cardLimit.FindStringSubmatch(c.OracleText)
+	switch {
+	case m == nil:
+		return 1, true
+	case m[1] == "":
+		return 0, false
+	}
+	if n, ok := numberWords[strings.ToLower(m[1])]; ok {
+		return n, true
+	}
+	if n, err := strconv.Atoi(m[1]); err == nil {
+		return n, true
+	}
+	return 1, true
+}
+
+func checkColorIdentity(commanders, cards []DeckCard) []Violation {
+	if len(commanders) == 0 {
+		return nil
+	}
+
+	allowed := map[Color]bool{}
+	for _, c := range commanders {
+		for _, color := range c.ColorIdentity {
+			allowed[color] = true
+		}
+	}
+
+	var violations []Violation
+	for _, c := range cards {
+		if c.Board == BoardCommanders {
+			continue
+		}
+		for _, color := range c.ColorIdentity {
+			if allowed[color] {
+				continue
+			}
+			violations = append(violations, Violation{
+				Rule:    RuleColorIdentity,
+				Card:    c.Name,
+				Board:   c.Board,
+				Message: fmt.Sprintf("%s is outside the commanders' color identity", c.Name),
+			})
+			break
+		}
+	}
+	return violations
+}
+
+func checkCommanders(commanders []DeckCard) []Violation {
+	violate := func(c DeckCard, format string, args ...any) []Violation {
+		return []Violation{{
+			Rule:    RuleCommander,
+			Card:    c.Name,
+			Board:   c.Board,
+			Message: fmt.Sprintf(format, args...),
+		}}
+	}
+
+	switch len(commanders) {
+	case 0:
+		return []Violation{{Rule: RuleCommander, Message: "deck has no commander"}}
+
+	case 1:
+		c := commanders[0]
+		if !c.canCommand() {
+			return violate(c, "%s can't be a commander", c.Name)
+		}
+		return nil
+
+	case 2:
+		a, b := commanders[0], commanders[1]
+		var violations []Violation
+		for _, c := range commanders {
+			if !c.canCommand() && !c.isBackground() {
+				violations = append(violations, violate(c, "%s can't be a commander", c.Name)...)
+			}
+		}
+		if len(violations) > 0 {
+			return violations
+		}
+		if !canPair(a, b) {
+			return violate(b, "%s and %s can't be commanders together", a.Name, b.Name)
+		}
+		return nil
+	}
+
+	return []Violation{{
+		Rule:    RuleCommander,
+		Message: fmt.Sprintf("deck has %d commanders but may have at most 2", len(commanders)),
+	}}
+}
+
+// isBasic reports if the card is a basic land, snow basics and Wastes
+// included.
+func (c DeckCard) isBasic() bool {
+	front, _, _ := strings.Cut(c.TypeLine, "//")
+	return strings.Contains(front, "Basic") && c.IsLand()
+}
+
+// canCommand reports if the card can be a commander on its own merits.
+func (c DeckCard) canCommand() bool {
+	front, _, _ := strings.Cut(c.TypeLine, "//")
+	legendary := strings.Contains(front, "Legendary")
+	return legendary && slices.Contains(c.Types(), "Creature") ||
+		strings.Contains(c.OracleText, "can be your commander")
+}
+
+// isBackground reports if the card is a Background enchantment.
+func (c DeckCard) isBackground() bool {
+	front, _, _ := strings.Cut(c.TypeLine, "//")
+	return strings.Contains(front, "Background")
+}
+
+// abilities lists the lines of the card's oracle text with any reminder text
+// removed.
+func (c DeckCard) abilities() []string {
+	var lines []string
+	for _, line := range strings.Split(c.OracleText, "\n") {
+		if i := strings.Index(line, "("); i >= 0 {
+			line = line[:i]
+		}
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// hasAbility reports if the card has the keyword ability.
+func (c DeckCard) hasAbility(keyword string) bool {
+	return slices.Contains(c.abilities(), keyword)
+}
+
+// partnerWith is the card named by a "Partner with" ability.
+func (c DeckCard) partnerWith() string {
+	for _, line := range c.abilities() {
+		if name, ok := strings.CutPrefix(line, "Partner with "); ok {
+			return name
+		}
+	}
+	return ""
+}
+
+// partnerVariant is the kind of a "Partner—Survivors" style ability which may
+// only pair with the same kind.
+func (c DeckCard) partnerVariant() string {
+	for _, line := range c.abilities() {
+		if kind, ok := strings.CutPrefix(line, "Partner—"); ok {
+			return kind
+		}
+	}
+	return ""
+}
+
+// canPair reports if the two cards are allowed to be commanders together.
+func canPair(a, b DeckCard) bool {
+	pairs := func(a, b DeckCard) bool {
+		switch {
+		case a.hasAbility("Partner") && b.hasAbility("Partner"):
+			return true
+		case a.partnerWith() != "" && a.partnerWith() == b.Name && b.partnerWith() == a.Name:
+			return true
+		case a.partnerVariant() != "" && a.partnerVariant() == b.partnerVariant():
+			return true
+		case a.hasAbility("Friends forever") && b.hasAbility("Friends forever"):
+			return true
+		case a.hasAbility("Choose a Background") && b.isBackground() && !b.canCommand():
+			return true
+		case a.hasAbility("Doctor's companion") && strings.Contains(b.TypeLine, "Time Lord Doctor"):
+			return true
+		}
+		return false
+	}
+	return pairs(a, b) || pairs(b, a)
+}
+
+func checkCompanions(companions, cards []DeckCard) []Violation {
+	if len(companions) > 1 {
+		return []Violation{{
+			Rule:    RuleCompanion,
+			Message: fmt.Sprintf("deck has %d companions but may have at most 1", len(companions)),
+		}}
+	}
+
+	var violations []Violation
+	for _, companion := range companions {
+		allowed, ok := companionRestrictions[companion.Name]
+		if !ok {
+			continue
+		}
+
+		for _, c := range cards {
+			if allowed(c, cards) {
+				continue
+			}
+			violations = append(violations, Violation{
+				Rule:    RuleCompanion,
+				Card:    c.Name,
+				Board:   c.Board,
+				Message: fmt.Sprintf("%s breaks the deck building restriction of %s", c.Name, companion.Name),
+			})
+		}
+
+		// Yorion needs 20 cards more than the minimum which a Commander deck
+		// can never have.
+		if companion.Name == "Yorion, Sky Nomad" {
+			violations = append(violations, Violation{
+				Rule:    RuleCompanion,
+				Card:    companion.Name,
+				Board:   companion.Board,
+				Message: fmt.Sprintf("%s needs a deck of at least %d cards", companion.Name, commanderDeckSize+20),
+			})
+		}
+	}
+	return violations
+}
+
+// companionRestrictions reports if a card in the starting deck meets the
+// restriction of each companion.
+var companionRestrictions = map[string]func(c DeckCard, deck []DeckCard) bool{
+	"Gyruda, Doom of Depths": func(c DeckCard, _ []DeckCard) bool {
+		return int(c.CMC)%2 == 0
+	},
+	"Jegantha, the Wellspring": func(c DeckCard, _ []DeckCard) bool {
+		cost, _, _ := strings.Cut(c.ManaCost, "//")
+		symbols := strings.SplitAfter(cost, "}")
+		seen := map[string]bool{}
+		for _, s := range symbols {
+			s = strings.TrimSpace(s)
+			if s == "" {
+				continue
+			}
+			if seen[s] {
+				return false
+			}
+			seen[s] = true
+		}
+		return true
+	},
+	"Kaheera, the Orphanguard": func(c DeckCard, _ []DeckCard) bool {
+		if !slices.Contains(c.Types(), "Creature") {
+			return true
+		}
+		front, _, _ := strings.Cut(c.TypeLine, "//")
+		_, subtypes, _ := strings.Cut(front, "—")
+		for _, t := range strings.Fields(subtypes) {
+			if slices.Contains(kaheeraTypes, t) {
+				return true
+			}
+		}
+		return false
+	},
+	"Keruga, the Macrosage": func(c DeckCard, _ []DeckCard) bool {
+		return c.IsLand() || c.CMC >= 3
+	},
+	"Lurrus of the Dream-Den": func(c DeckCard, _ []DeckCard) bool {
+		return !c.isPermanent() || c.CMC <= 2
+	},
+	"Lutri, the Spellchaser": func(c DeckCard, deck []DeckCard) bool {
+		if c.IsLand() {
+			return true
+		}
+		var copies int
+		for _, o := range deck {
+			if o.Name == c.Name {
+				copies += o.Quantity
+			}
+		}
+		return copies <= 1
+	},
+	"Obosh, the Preypiercer": func(c DeckCard, _ []DeckCard) bool {
+		return c.IsLand() || int(c.CMC)%2 == 1
+	},
+	"Umori, the Collector": func(c DeckCard, deck []DeckCard) bool {
+		return c.IsLand() || slices.Contains(c.Types(), sharedType(deck))
+	},
+	"Yorion, Sky Nomad": func(DeckCard, []DeckCard) bool {
+		return true
+	},
+	// Zirda needs every permanent to have an activated ability. Any colon in
+	// the oracle text, reminder text included, is taken to be one.
+	"Zirda, the Dawnwaker": func(c DeckCard, _ []DeckCard) bool {
+		return !c.isPermanent() || strings.Contains(c.OracleText, ":")
+	},
+}
+
+// isPermanent reports if the front face of the card is a permanent.
+func (c DeckCard) isPermanent() bool {
+	for _, t := range c.Types() {
+		if slices.Contains(permanentTypes, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// sharedType is the card type found on the most nonland cards in the deck.
+// Cards without it are the ones keeping the deck from meeting Umori's
+// restriction.
+func sharedType(deck []DeckCard) string {
+	counts := map[string]int{}
+	for _, c := range deck {
+		if c.IsLand() {
+			continue
+		}
+		for _, t := range c.Types() {
+			counts[t] += c.Quantity
+		}
+	}
+
+	var best string
+	for _, t := range cardTypes {
+		if counts[t] > counts[best] {
+			best = t
+		}
+	}
+	return best
+}
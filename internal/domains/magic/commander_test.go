@@ -0,0 +1,179 @@
+package magic
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCheckCommander(t *testing.T) {
+
+	// deck is a legal 100 card deck: Arahbo, 33 basics and 66 cats.
+	deck := func() Deck {
+		d := Deck{
+			Format: "commander",
+			Cards: []DeckCard{
+				{Board: BoardCommanders, Quantity: 1, Name: "Arahbo, Roar of the World", TypeLine: "Legendary Creature — Cat Avatar", ManaCost: "{3}{G}{W}", CMC: 5, ColorIdentity: Selesnya},
+				{Board: BoardMainboard, Quantity: 30, Name: "Plains", TypeLine: "Basic Land — Plains", ColorIdentity: MonoWhite},
+				{Board: BoardMainboard, Quantity: 3, Name: "Snow-Covered Forest", TypeLine: "Basic Snow Land — Forest", ColorIdentity: MonoGreen},
+				{Board: BoardMaybeboard, Quantity: 4, Name: "Lightning Bolt", TypeLine: "Instant", ColorIdentity: MonoRed},
+			},
+		}
+		for i := 1; i <= 66; i++ {
+			d.Cards = append(d.Cards, DeckCard{
+				Board:         BoardMainboard,
+				Quantity:      1,
+				Name:          fmt.Sprintf("Cat %d", i),
+				TypeLine:      "Creature — Cat",
+				ManaCost:      "{1}{W}",
+				CMC:           2,
+				ColorIdentity: MonoWhite,
+			})
+		}
+		return d
+	}
+
+	// last is the last cat in the deck which edits replace to keep the deck
+	// at 100 cards.
+	last := func(d *Deck) *DeckCard { return &d.Cards[len(d.Cards)-1] }
+
+	partner := func(d *Deck, c DeckCard) {
+		c.Board = BoardCommanders
+		c.Quantity = 1
+		*last(d) = c
+	}
+
+	companion := func(d *Deck, name string) {
+		d.Cards = append(d.Cards, DeckCard{Board: BoardCompanions, Quantity: 1, Name: name, TypeLine: "Legendary Creature — Cat Beast", ColorIdentity: MonoWhite})
+	}
+
+	tests := []struct {
+		name string
+		edit func(d *Deck)
+		want []string
+	}{
+		{"valid", func(d *Deck) {}, nil},
+		{"too few cards", func(d *Deck) { d.Cards = d.Cards[:len(d.Cards)-1] }, []string{RuleDeckSize}},
+		{"too many cards", func(d *Deck) { d.Cards[1].Quantity++ }, []string{RuleDeckSize}},
+		{"duplicate card", func(d *Deck) { d.Cards[4].Name = d.Cards[5].Name }, []string{RuleSingleton}},
+		{"any number", func(d *Deck) {
+			d.Cards = d.Cards[:len(d.Cards)-10]
+			d.Cards = append(d.Cards, DeckCard{Board: BoardMainboard, Quantity: 10, Name: "Persistent Petitioners", TypeLine: "Creature — Human Advisor", OracleText: "{1}, {T}: Target player mills a card.\nA deck can have any number of cards named Persistent Petitioners.", ColorIdentity: Colorless})
+		}, nil},
+		{"up to seven", func(d *Deck) {
+			d.Cards = d.Cards[:len(d.Cards)-7]
+			d.Cards = append(d.Cards, DeckCard{Board: BoardMainboard, Quantity: 7, Name: "Seven Dwarves", TypeLine: "Creature — Dwarf", OracleText: "A deck can have up to seven cards named Seven Dwarves.", ColorIdentity: Colorless})
+		}, nil},
+		{"more than seven", func(d *Deck) {
+			d.Cards = d.Cards[:len(d.Cards)-8]
+			d.Cards = append(d.Cards, DeckCard{Board: BoardMainboard, Quantity: 8, Name: "Seven Dwarves", TypeLine: "Creature — Dwarf", OracleText: "A deck can have up to seven cards named Seven Dwarves.", ColorIdentity: Colorless})
+		}, []string{RuleSingleton}},
+		{"off color", func(d *Deck) { last(d).ColorIdentity = Boros }, []string{RuleColorIdentity}},
+		{"maybeboard is ignored", func(d *Deck) { d.Cards[3].Quantity = 40 }, nil},
+		{"no commander", func(d *Deck) { d.Cards[0].Board = BoardMainboard }, []string{RuleCommander}},
+		{"not legendary", func(d *Deck) { d.Cards[0].TypeLine = "Creature — Cat Avatar" }, []string{RuleCommander}},
+		{"planeswalker commander", func(d *Deck) {
+			d.Cards[0].TypeLine = "Legendary Planeswalker — Ajani"
+			d.Cards[0].OracleText = "Ajani can be your commander."
+		}, nil},
+		{"partners", func(d *Deck) {
+			d.Cards[0].OracleText = "Partner (You can have two commanders if both have partner.)"
+			partner(d, DeckCard{Name: "Kydele, Chosen of Kruphix", TypeLine: "Legendary Creature — Human Wizard", OracleText: "Partner (You can have two commanders if both have partner.)", ColorIdentity: Simic})
+		}, nil},
+		{"one partner", func(d *Deck) {
+			partner(d, DeckCard{Name: "Kydele, Chosen of Kruphix", TypeLine: "Legendary Creature — Human Wizard", OracleText: "Partner (You can have two commanders if both have partner.)", ColorIdentity: Simic})
+		}, []string{RuleCommander}},
+		{"partner with", func(d *Deck) {
+			d.Cards[0].Name = "Pir, Imaginative Rascal"
+			d.Cards[0].OracleText = "Partner with Toothy, Imaginary Friend (When this creature enters, target player may put Toothy into their hand from their library, then shuffle.)"
+			partner(d, DeckCard{Name: "Toothy, Imaginary Friend", TypeLine: "Legendary Creature — Illusion", OracleText: "Partner with Pir, Imaginative Rascal (When this creature enters, target player may put Pir into their hand from their library, then shuffle.)", ColorIdentity: MonoBlue})
+		}, nil},
+		{"partner with someone else", func(d *Deck) {
+			d.Cards[0].OracleText = "Partner with Toothy, Imaginary Friend"
+			partner(d, DeckCard{Name: "Kydele, Chosen of Kruphix", TypeLine: "Legendary Creature — Human Wizard", OracleText: "Partner", ColorIdentity: Simic})
+		}, []string{RuleCommander}},
+		{"background", func(d *Deck) {
+			d.Cards[0].OracleText = "Choose a Background (You can have a Background as a second commander.)"
+			partner(d, DeckCard{Name: "Raised by Giants", TypeLine: "Legendary Enchantment — Background", ColorIdentity: MonoGreen})
+		}, nil},
+		{"background without choose a background", func(d *Deck) {
+			partner(d, DeckCard{Name: "Raised by Giants", TypeLine: "Legendary Enchantment — Background", ColorIdentity: MonoGreen})
+		}, []string{RuleCommander}},
+		{"friends forever", func(d *Deck) {
+			d.Cards[0].OracleText = "Friends forever (You can have two commanders if both have friends forever.)"
+			partner(d, DeckCard{Name: "Will the Wise", TypeLine: "Legendary Creature — Human Cleric", OracleText: "Friends forever (You can have two commanders if both have friends forever.)", ColorIdentity: MonoGreen})
+		}, nil},
+		{"partner variants", func(d *Deck) {
+			d.Cards[0].OracleText = "Partner—Survivors (You can have two commanders if both have this ability.)"
+			partner(d, DeckCard{Name: "Tyvar the Pummeler", TypeLine: "Legendary Creature — Elf Warrior", OracleText: "Partner—Survivors", ColorIdentity: MonoGreen})
+		}, nil},
+		{"different partner variants", func(d *Deck) {
+			d.Cards[0].OracleText = "Partner—Survivors"
+			partner(d, DeckCard{Name: "Laurine, the Diversion", TypeLine: "Legendary Creature — Human Rogue", OracleText: "Partner—Character select", ColorIdentity: MonoGreen})
+		}, []string{RuleCommander}},
+		{"doctor's companion", func(d *Deck) {
+			d.Cards[0].OracleText = "Doctor's companion (You can have two commanders if the other is the Doctor.)"
+			partner(d, DeckCard{Name: "The Fourteenth Doctor", TypeLine: "Legendary Creature — Time Lord Doctor", ColorIdentity: MonoWhite})
+		}, nil},
+		{"three commanders", func(d *Deck) {
+			for i := 4; i < 6; i++ {
+				d.Cards[i].Board = BoardCommanders
+				d.Cards[i].TypeLine = "Legendary Creature — Cat"
+				d.Cards[i].OracleText = "Partner"
+			}
+			d.Cards[0].OracleText = "Partner"
+		}, []string{RuleCommander}},
+		{"jegantha", func(d *Deck) { companion(d, "Jegantha, the Wellspring") }, nil},
+		{"jegantha with a double pip", func(d *Deck) { companion(d, "Jegantha, the Wellspring"); d.Cards[4].ManaCost = "{W}{W}" }, []string{RuleCompanion}},
+		{"lurrus", func(d *Deck) { companion(d, "Lurrus of the Dream-Den") }, []string{RuleCompanion}},
+		{"gyruda", func(d *Deck) { companion(d, "Gyruda, Doom of Depths") }, []string{RuleCompanion}},
+		{"kaheera", func(d *Deck) { companion(d, "Kaheera, the Orphanguard") }, nil},
+		{"kaheera with a human", func(d *Deck) { companion(d, "Kaheera, the Orphanguard"); d.Cards[4].TypeLine = "Creature — Human" }, []string{RuleCompanion}},
+		{"umori", func(d *Deck) { companion(d, "Umori, the Collector") }, nil},
+		{"umori with a sorcery", func(d *Deck) { companion(d, "Umori, the Collector"); d.Cards[4].TypeLine = "Sorcery" }, []string{RuleCompanion}},
+		{"yorion", func(d *Deck) { companion(d, "Yorion, Sky Nomad") }, []string{RuleCompanion}},
+		{"two companions", func(d *Deck) { companion(d, "Jegantha, the Wellspring"); companion(d, "Kaheera, the Orphanguard") }, []string{RuleCompanion}},
+		{"off color companion", func(d *Deck) { companion(d, "Jegantha, the Wellspring"); last(d).ColorIdentity = MonoRed }, []string{RuleColorIdentity}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := deck()
+			tt.edit(&d)
+
+			var got []string
+			for _, v := range CheckCommander(d) {
+				got = append(got, v.Rule)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("wrong violations:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestValidateDeckCommander(t *testing.T) {
+	deck := Deck{
+		ID:     "deck",
+		Format: "commander",
+		Cards: []DeckCard{
+			{Board: BoardCommanders, Quantity: 1, Name: "Arahbo, Roar of the World", TypeLine: "Legendary Creature — Cat Avatar", ColorIdentity: Selesnya, Legalities: Legalities{"commander": LegalityLegal}},
+			{Board: BoardMainboard, Quantity: 98, Name: "Plains", TypeLine: "Basic Land — Plains", ColorIdentity: MonoWhite, Legalities: Legalities{"commander": LegalityLegal}},
+			{Board: BoardMainboard, Quantity: 1, Name: "Lightning Bolt", TypeLine: "Instant", ColorIdentity: MonoRed, Legalities: Legalities{"commander": LegalityLegal}},
+		},
+	}
+
+	want := DeckValidation{
+		DeckID: "deck",
+		Format: "commander",
+		Violations: []Violation{
+			{Rule: RuleColorIdentity, Card: "Lightning Bolt", Board: BoardMainboard, Message: "Lightning Bolt is outside the commanders' color identity"},
+		},
+		Unchecked: []string{},
+	}
+
+	if diff := cmp.Diff(want, ValidateDeck(deck)); diff != "" {
+		t.Errorf("wrong validation:\n%s", diff)
+	}
+}
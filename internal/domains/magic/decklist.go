@@ -0,0 +1,138 @@
+package magic
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidDecklist = errors.New("invalid decklist")
+)
+
+// sections maps the headers used by Arena, MTGO and most deck sites to the
+// board the cards under them belong to.
+var sections = map[string]string{
+	"commander":        BoardCommanders,
+	"commanders":       BoardCommanders,
+	"oathbreaker":      BoardCommanders,
+	"companion":        BoardCompanions,
+	"signature spell":  BoardSignatureSpells,
+	"signature spells": BoardSignatureSpells,
+	"deck":             BoardMainboard,
+	"main":             BoardMainboard,
+	"mainboard":        BoardMainboard,
+	"sideboard":        BoardSideboard,
+	"maybeboard":       BoardMaybeboard,
+	"considering":      BoardMaybeboard,
+}
+
+// cardLine matches lines like "1 Sol Ring", "1x Sol Ring" or
+// "1 Sol Ring (CMM) 410 *F*".
+var cardLine = regexp.MustCompile(`^(\d+)x?\s+(.+?)(?:\s+\(([A-Za-z0-9]+)\)(?:\s+([^\s*]+))?)?(?:\s+\*([FE])\*)?$`)
+
+// ParseDecklist reads a plain text decklist as exported by Arena, MTGO or most
+// deck building sites. Cards only have the fields that can be read from the
+// text: board, quantity, name, and the printing and finish if given.
+//
+// Sections are started by headers such as "Commander" or "Sideboard". A blank
+// line ends a commander or companion section. Lists without any headers follow
+// the MTGO convention where cards after the first blank line are the
+// sideboard.
+func ParseDecklist(text string) ([]DeckCard, error) {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+
+	hasHeaders := false
+	for _, line := range lines {
+		if _, ok := sectionHeader(line); ok {
+			hasHeaders = true
+			break
+		}
+	}
+
+	var (
+		cards []DeckCard
+		board = BoardMainboard
+		skip  bool // Inside a section like Arena's "About" that has no cards
+	)
+
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+
+		switch {
+		case line == "":
+			switch {
+			case !hasHeaders && len(cards) > 0:
+				board = BoardSideboard
+			case board == BoardCommanders, board == BoardCompanions, board == BoardSignatureSpells:
+				board = BoardMainboard
+			}
+			continue
+
+		case strings.HasPrefix(line, "//"), strings.HasPrefix(line, "#"):
+			continue
+
+		case strings.EqualFold(line, "about"):
+			skip = true
+			continue
+		}
+
+		if b, ok := sectionHeader(line); ok {
+			board, skip = b, false
+			continue
+		}
+		if skip {
+			continue
+		}
+
+		cardBoard := board
+		if rest, ok := strings.CutPrefix(line, "SB:"); ok {
+			line = strings.TrimSpace(rest)
+			cardBoard = BoardSideboard
+		}
+
+		m := cardLine.FindStringSubmatch(line)
+		if m == nil {
+			return nil, fmt.Errorf("%w: line %d: could not read %q", ErrInvalidDecklist, i+1, line)
+		}
+
+		qty, err := strconv.Atoi(m[1])
+		if err != nil || qty < 1 {
+			return nil, fmt.Errorf("%w: line %d: bad quantity %q", ErrInvalidDecklist, i+1, m[1])
+		}
+
+		card := DeckCard{
+			Board:           cardBoard,
+			Quantity:        qty,
+			Name:            m[2],
+			Set:             strings.ToLower(m[3]),
+			CollectorNumber: m[4],
+			Finish:          "nonFoil",
+		}
+		switch m[5] {
+		case "F":
+			card.Finish = "foil"
+		case "E":
+			card.Finish = "etched"
+		}
+
+		cards = append(cards, card)
+	}
+
+	if len(cards) == 0 {
+		return nil, fmt.Errorf("%w: no cards found", ErrInvalidDecklist)
+	}
+
+	return cards, nil
+}
+
+// sectionHeader reports whether the line starts a new section and which board
+// that section is.
+func sectionHeader(line string) (string, bool) {
+	line = strings.ToLower(strings.TrimSpace(line))
+	line = strings.TrimSuffix(line, ":")
+	board, ok := sections[line]
+	return board, ok
+}
@@ -0,0 +1,96 @@
+package magic
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseDecklist(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []DeckCard
+	}{
+		{
+			name: "arena",
+			text: `About
+Name Vial Smasher
+
+Commander
+1 Vial Smasher the Fierce (CMR) 491
+1 Sakashima of a Thousand Faces (CMR) 89
+
+Deck
+1 Sol Ring (CMM) 410 *F*
+12 Island
+
+Sideboard
+1 Pyroblast
+`,
+			want: []DeckCard{
+				{Board: BoardCommanders, Quantity: 1, Name: "Vial Smasher the Fierce", Set: "cmr", CollectorNumber: "491", Finish: "nonFoil"},
+				{Board: BoardCommanders, Quantity: 1, Name: "Sakashima of a Thousand Faces", Set: "cmr", CollectorNumber: "89", Finish: "nonFoil"},
+				{Board: BoardMainboard, Quantity: 1, Name: "Sol Ring", Set: "cmm", CollectorNumber: "410", Finish: "foil"},
+				{Board: BoardMainboard, Quantity: 12, Name: "Island", Finish: "nonFoil"},
+				{Board: BoardSideboard, Quantity: 1, Name: "Pyroblast", Finish: "nonFoil"},
+			},
+		},
+		{
+			name: "mtgo",
+			text: "4 Lightning Bolt\r\n20 Mountain\r\n\r\n3 Smash to Smithereens\r\n",
+			want: []DeckCard{
+				{Board: BoardMainboard, Quantity: 4, Name: "Lightning Bolt", Finish: "nonFoil"},
+				{Board: BoardMainboard, Quantity: 20, Name: "Mountain", Finish: "nonFoil"},
+				{Board: BoardSideboard, Quantity: 3, Name: "Smash to Smithereens", Finish: "nonFoil"},
+			},
+		},
+		{
+			name: "headers with colons and prefixes",
+			text: `// Exported list
+Commander:
+1x Arahbo, Roar of the World
+Companion:
+1x Kaheera, the Orphanguard
+
+1x Fire // Ice
+SB: 1x Swords to Plowshares
+Maybeboard:
+1x Jetmir, Nexus of Revels (SNC) 128 *E*
+`,
+			want: []DeckCard{
+				{Board: BoardCommanders, Quantity: 1, Name: "Arahbo, Roar of the World", Finish: "nonFoil"},
+				{Board: BoardCompanions, Quantity: 1, Name: "Kaheera, the Orphanguard", Finish: "nonFoil"},
+				{Board: BoardMainboard, Quantity: 1, Name: "Fire // Ice", Finish: "nonFoil"},
+				{Board: BoardSideboard, Quantity: 1, Name: "Swords to Plowshares", Finish: "nonFoil"},
+				{Board: BoardMaybeboard, Quantity: 1, Name: "Jetmir, Nexus of Revels", Set: "snc", CollectorNumber: "128", Finish: "etched"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseDecklist(tt.text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("wrong cards:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParseDecklistErrors(t *testing.T) {
+	for _, text := range []string{
+		"",
+		"Deck\n\n",
+		"Sol Ring",
+		"0 Sol Ring",
+	} {
+		if _, err := ParseDecklist(text); !errors.Is(err, ErrInvalidDecklist) {
+			t.Errorf("parsing %q should fail with ErrInvalidDecklist but got %v", text, err)
+		}
+	}
+}
@@ -3,12 +3,18 @@ package magic
 import (
 	"database/sql/driver"
 	"encoding/json"
+	"errors"
 	"time"
 )
 
+var (
+	ErrDeckNotFound = errors.New("deck not found")
+)
+
 type Deck struct {
 	ID            string        `db:"id" json:"id"`
 	UserID        string        `db:"user_id" json:"user_id"`
+	AccountID     string        `db:"account_id" json:"account_id"`
 	Service       string        `db:"service" json:"service"`
 	ServiceID     string        `db:"service_id" json:"service_id"`
 	Name          string        `db:"name" json:"name"`
@@ -17,8 +23,57 @@ type Deck struct {
 	ColorIdentity ColorIdentity `db:"color_identity" json:"color_identity"`
 	Archetypes    Archetypes    `db:"archetypes" json:"archetypes"`
 	Leaders       Leaders       `db:"leaders" json:"leaders"`
+	Version       int           `db:"version" json:"version"`
 	UpdatedAt     time.Time     `db:"updated_at" json:"updated_at"`
 	RefreshedAt   time.Time     `db:"refreshed_at" json:"refreshed_at"`
+	DeletedAt     *time.Time    `db:"deleted_at" json:"deleted_at,omitempty"`
+
+	// PricesCheckedAt is when prices were last fetched for the deck's cards.
+	PricesCheckedAt *time.Time `db:"prices_checked_at" json:"-"`
+
+	// VersionsCheckedAt is when the deck's version was last compared to its
+	// service.
+	VersionsCheckedAt *time.Time `db:"versions_checked_at" json:"-"`
+
+	// Cards is only populated when the deck's contents are explicitly loaded.
+	Cards []DeckCard `db:"-" json:"cards,omitempty"`
+}
+
+// Boards a card can be on within a deck.
+const (
+	BoardCommanders      = "commanders"
+	BoardCompanions      = "companions"
+	BoardSignatureSpells = "signature_spells"
+	BoardMainboard       = "mainboard"
+	BoardSideboard       = "sideboard"
+	BoardMaybeboard      = "maybeboard"
+)
+
+// DeckCard is one entry in a deck's card list along with the card data we
+// need to compute stats.
+type DeckCard struct {
+	ID              string        `db:"id" json:"-"`
+	DeckID          string        `db:"deck_id" json:"-"`
+	Board           string        `db:"board" json:"board"`
+	Quantity        int           `db:"quantity" json:"quantity"`
+	Finish          string        `db:"finish" json:"finish"`
+	IsProxy         bool          `db:"is_proxy" json:"is_proxy"`
+	ScryfallID      string        `db:"scryfall_id" json:"scryfall_id"`
+	Name            string        `db:"name" json:"name"`
+	Set             string        `db:"set_code" json:"set"`
+	CollectorNumber string        `db:"collector_number" json:"collector_number"`
+	Rarity          string        `db:"rarity" json:"rarity"`
+	ManaCost        string        `db:"mana_cost" json:"mana_cost"`
+	CMC             float64       `db:"cmc" json:"cmc"`
+	TypeLine        string        `db:"type_line" json:"type_line"`
+	OracleText      string        `db:"oracle_text" json:"oracle_text"`
+	Colors          ColorIdentity `db:"colors" json:"colors"`
+	ColorIdentity   ColorIdentity `db:"color_identity" json:"color_identity"`
+	Legalities      Legalities    `db:"legalities" json:"legalities,omitempty"`
+
+	// Prices are only set on cards fresh from a service. Stored prices are
+	// kept as CardPrice history.
+	Prices []Price `db:"-" json:"prices,omitempty"`
 }
 
 type Leaders struct {
@@ -33,6 +88,10 @@ type Card struct {
 	Name string
 }
 
+// Legalities maps a format's legality key to the card's status in it such as
+// "legal" or "banned".
+type Legalities map[string]string
+
 type Archetypes []Archetype
 
 type Archetype struct {
@@ -77,3 +136,24 @@ func (a *Leaders) Scan(v interface{}) error {
 func (a Leaders) Value() (driver.Value, error) {
 	return json.Marshal(a)
 }
+
+// Scan implements the Scanner interface for Legalities.
+func (l *Legalities) Scan(v interface{}) error {
+	if v == nil {
+		return nil
+	}
+	b, ok := v.([]byte)
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(b, l)
+}
+
+// Value implements the Valuer interface for Legalities. The column can't be
+// null so cards without legalities are stored as an empty object.
+func (l Legalities) Value() (driver.Value, error) {
+	if l == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(l)
+}
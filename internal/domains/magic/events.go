@@ -0,0 +1,153 @@
+package magic
+
+import (
+	"sync"
+)
+
+// Values for RefreshEvent.Type.
+const (
+	EventStarted     = "started"
+	EventDiscovered  = "discovered"
+	EventDeck        = "deck"
+	EventError       = "error"
+	EventInterrupted = "interrupted"
+	EventCompleted   = "completed"
+)
+
+// Values for DeckProgress.State.
+const (
+	DeckNew      = "new"
+	DeckStale    = "stale"
+	DeckRestored = "restored"
+	DeckUpToDate = "up_to_date"
+	DeckDeleted  = "deleted"
+)
+
+// RefreshEvent describes progress of a refresh as it happens.
+type RefreshEvent struct {
+	Type      string         `json:"type"`
+	AccountID string         `json:"account_id"`
+	Decks     int            `json:"decks,omitempty"`
+	Deck      *DeckProgress  `json:"deck,omitempty"`
+	Error     string         `json:"error,omitempty"`
+	Retrying  bool           `json:"retrying,omitempty"`
+	Totals    *RefreshTotals `json:"totals,omitempty"`
+}
+
+// DeckProgress reports what a refresh did with a single deck.
+type DeckProgress struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	State string `json:"state"`
+}
+
+// RefreshTotals counts what a refresh did with all of the account's decks.
+type RefreshTotals struct {
+	Found    int `json:"found"`
+	New      int `json:"new"`
+	Stale    int `json:"stale"`
+	Restored int `json:"restored"`
+	UpToDate int `json:"up_to_date"`
+	Deleted  int `json:"deleted"`
+}
+
+// add counts a deck in the totals according to its state.
+func (t *RefreshTotals) add(state string) {
+	switch state {
+	case DeckNew:
+		t.New++
+	case DeckStale:
+		t.Stale++
+	case DeckRestored:
+		t.Restored++
+	case DeckUpToDate:
+		t.UpToDate++
+	case DeckDeleted:
+		t.Deleted++
+	}
+}
+
+// broker fans refresh events out to anyone watching an account. It only knows
+// about refreshes running in this process.
+type broker struct {
+	mu     sync.Mutex
+	subs   map[string]map[chan RefreshEvent]struct{}
+	closed bool
+}
+
+func newBroker() *broker {
+	return &broker{subs: map[string]map[chan RefreshEvent]struct{}{}}
+}
+
+// subscribe returns a channel of events for the account and a function to
+// stop receiving them. The channel is closed when the broker closes.
+func (b *broker) subscribe(accountID string) (<-chan RefreshEvent, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ch := make(chan RefreshEvent, 64)
+	if b.closed {
+		close(ch)
+		return ch, func() {}
+	}
+
+	if b.subs[accountID] == nil {
+		b.subs[accountID] = map[chan RefreshEvent]struct{}{}
+	}
+	b.subs[accountID][ch] = struct{}{}
+
+	unsubscribe := func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subs[accountID][ch]; !ok {
+			return
+		}
+		delete(b.subs[accountID], ch)
+		if len(b.subs[accountID]) == 0 {
+			delete(b.subs, accountID)
+		}
+		close(ch)
+	}
+
+	return ch, unsubscribe
+}
+
+// publish sends the event to every subscriber of its account. Subscribers that
+// aren't keeping up miss events rather than slowing down the refresh.
+func (b *broker) publish(ev RefreshEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subs[ev.AccountID] {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
+
+// close ends every subscription and refuses new ones.
+func (b *broker) close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for accountID, subs := range b.subs {
+		for ch := range subs {
+			close(ch)
+		}
+		delete(b.subs, accountID)
+	}
+}
+
+// SubscribeRefresh streams progress events for refreshes of the account. Call
+// the returned function when done listening.
+func (s *Service) SubscribeRefresh(accountID string) (<-chan RefreshEvent, func()) {
+	return s.events.subscribe(accountID)
+}
+
+// CloseRefreshSubscriptions ends every event stream so long lived requests
+// don't hold up a server shutdown.
+func (s *Service) CloseRefreshSubscriptions() {
+	s.events.close()
+}
@@ -0,0 +1,40 @@
+package magic
+
+import (
+	"testing"
+)
+
+func TestBroker(t *testing.T) {
+	b := newBroker()
+
+	events, unsubscribe := b.subscribe("account-1")
+	other, _ := b.subscribe("account-2")
+
+	b.publish(RefreshEvent{Type: EventStarted, AccountID: "account-1"})
+
+	ev := <-events
+	if ev.Type != EventStarted {
+		t.Errorf("should receive %q event but got %q", EventStarted, ev.Type)
+	}
+	select {
+	case ev := <-other:
+		t.Errorf("other account should not receive events but got %+v", ev)
+	default:
+	}
+
+	unsubscribe()
+	if _, ok := <-events; ok {
+		t.Error("channel should be closed after unsubscribing")
+	}
+	unsubscribe() // Calling twice must not panic.
+
+	b.close()
+	if _, ok := <-other; ok {
+		t.Error("channel should be closed when the broker closes")
+	}
+
+	late, _ := b.subscribe("account-1")
+	if _, ok := <-late; ok {
+		t.Error("subscribing to a closed broker should give a closed channel")
+	}
+}
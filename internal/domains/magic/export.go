@@ -0,0 +1,244 @@
+package magic
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrUnknownExportFormat = errors.New("unknown export format")
+)
+
+// Formats a deck can be exported to.
+const (
+	ExportArena = "arena"
+	ExportMTGO  = "mtgo"
+	ExportCSV   = "csv"
+	ExportJSON  = "json"
+)
+
+// ExportContentTypes maps each export format to the content type it should be
+// served as.
+var ExportContentTypes = map[string]string{
+	ExportArena: "text/plain; charset=utf-8",
+	ExportMTGO:  "text/plain; charset=utf-8",
+	ExportCSV:   "text/csv; charset=utf-8",
+	ExportJSON:  "application/json",
+}
+
+// ExportDeck writes the deck's cards to w in one of the export formats. The
+// deck must have its cards loaded.
+func ExportDeck(w io.Writer, deck Deck, format string) error {
+	switch format {
+	case ExportArena:
+		return exportArena(w, deck)
+	case ExportMTGO:
+		return exportMTGO(w, deck)
+	case ExportCSV:
+		return exportCSV(w, deck)
+	case ExportJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(deck)
+	}
+	return fmt.Errorf("%w %q", ErrUnknownExportFormat, format)
+}
+
+// exportArena writes the deck in the sections Arena imports. Arena has nothing
+// for signature spells so they are listed with the oathbreaker under Commander
+// the same way Archidekt keeps them. ParseDecklist sorts them back out when
+// the list is imported as an oathbreaker deck.
+func exportArena(w io.Writer, deck Deck) error {
+	l := deck.Leaders
+	leaders, rest := splitLeaders(deck.Cards,
+		slices.Concat(l.Commanders, l.Oathbreakers, l.SignatureSpells),
+		companions(l),
+	)
+
+	sections := []struct {
+		header string
+		cards  []DeckCard
+	}{
+		{"Commander", leaders[0]},
+		{"Companion", leaders[1]},
+		{"Deck", cardsOn(rest, BoardMainboard)},
+		{"Sideboard", cardsOn(rest, BoardSideboard)},
+	}
+
+	first := true
+	for _, section := range sections {
+		cards := section.cards
+		if len(cards) == 0 {
+			continue
+		}
+
+		if !first {
+			fmt.Fprintln(w)
+		}
+		first = false
+
+		fmt.Fprintln(w, section.header)
+		for _, c := range cards {
+			line := fmt.Sprintf("%d %s", c.Quantity, c.Name)
+			if c.Set != "" && c.CollectorNumber != "" {
+				line += fmt.Sprintf(" (%s) %s", strings.ToUpper(c.Set), c.CollectorNumber)
+			}
+			if _, err := fmt.Fprintln(w, line); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// exportMTGO writes the main deck then a blank line and the sideboard. MTGO
+// expects commanders and companions in the sideboard so every leader goes
+// there too.
+func exportMTGO(w io.Writer, deck Deck) error {
+	l := deck.Leaders
+	leaders, rest := splitLeaders(deck.Cards,
+		slices.Concat(l.Commanders, l.Oathbreakers, l.SignatureSpells, companions(l)),
+	)
+
+	main := cardsOn(rest, BoardMainboard)
+	side := append(leaders[0], cardsOn(rest, BoardSideboard)...)
+
+	for _, c := range main {
+		if _, err := fmt.Fprintf(w, "%d %s\n", c.Quantity, c.Name); err != nil {
+			return err
+		}
+	}
+
+	if len(side) == 0 {
+		return nil
+	}
+
+	fmt.Fprintln(w)
+	for _, c := range side {
+		if _, err := fmt.Fprintf(w, "%d %s\n", c.Quantity, c.Name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// exportCSV writes one row per card including the maybeboard. Each kind of
+// leader gets its own section.
+func exportCSV(w io.Writer, deck Deck) error {
+	cw := csv.NewWriter(w)
+
+	header := []string{"section", "quantity", "name", "set", "collector_number", "finish", "is_proxy", "scryfall_id"}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	l := deck.Leaders
+	leaders, rest := splitLeaders(deck.Cards, l.Commanders, l.Oathbreakers, l.SignatureSpells, companions(l))
+
+	sections := []struct {
+		name  string
+		cards []DeckCard
+	}{
+		{"commanders", leaders[0]},
+		{"oathbreakers", leaders[1]},
+		{"signature_spells", leaders[2]},
+		{"companions", leaders[3]},
+		{BoardMainboard, cardsOn(rest, BoardMainboard)},
+		{BoardSideboard, cardsOn(rest, BoardSideboard)},
+		{BoardMaybeboard, cardsOn(rest, BoardMaybeboard)},
+	}
+	for _, section := range sections {
+		for _, c := range section.cards {
+			err := cw.Write([]string{
+				section.name,
+				strconv.Itoa(c.Quantity),
+				c.Name,
+				c.Set,
+				c.CollectorNumber,
+				c.Finish,
+				strconv.FormatBool(c.IsProxy),
+				c.ScryfallID,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// splitLeaders finds the deck card for each leader in each group and returns
+// them by group along with the cards that aren't leaders. A leader matches the
+// card with its Scryfall ID on a leader board, or failing that on any board,
+// so a companion also kept in the sideboard leaves that copy where it is.
+func splitLeaders(cards []DeckCard, groups ...[]Card) ([][]DeckCard, []DeckCard) {
+	used := make([]bool, len(cards))
+
+	find := func(id string) int {
+		match := -1
+		for i, c := range cards {
+			if used[i] || c.ScryfallID != id {
+				continue
+			}
+			switch c.Board {
+			case BoardCommanders, BoardSignatureSpells, BoardCompanions:
+				return i
+			}
+			if match < 0 {
+				match = i
+			}
+		}
+		return match
+	}
+
+	leaders := make([][]DeckCard, len(groups))
+	for g, group := range groups {
+		for _, leader := range group {
+			if i := find(leader.ID); i >= 0 {
+				used[i] = true
+				leaders[g] = append(leaders[g], cards[i])
+			}
+		}
+	}
+
+	var rest []DeckCard
+	for i, c := range cards {
+		if !used[i] {
+			rest = append(rest, c)
+		}
+	}
+
+	return leaders, rest
+}
+
+// companions returns the companion as a list for splitLeaders.
+func companions(l Leaders) []Card {
+	if l.Companion == nil {
+		return nil
+	}
+	return []Card{*l.Companion}
+}
+
+// cardsOn returns the cards on any of the boards in the order the boards are
+// given.
+func cardsOn(cards []DeckCard, boards ...string) []DeckCard {
+	var on []DeckCard
+	for _, board := range boards {
+		for _, c := range cards {
+			if c.Board == board {
+				on = append(on, c)
+			}
+		}
+	}
+	return on
+}
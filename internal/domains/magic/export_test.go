@@ -0,0 +1,156 @@
+package magic
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestExportDeck(t *testing.T) {
+	deck := Deck{
+		Name:   "Cats!",
+		Format: "commander",
+		Cards: []DeckCard{
+			{Board: BoardCommanders, Quantity: 1, ScryfallID: "arahbo", Name: "Arahbo, Roar of the World", Set: "c20", CollectorNumber: "2", Finish: "nonFoil"},
+			{Board: BoardSideboard, Quantity: 1, ScryfallID: "kaheera", Name: "Kaheera, the Orphanguard", Set: "iko", CollectorNumber: "224", Finish: "nonFoil"},
+			{Board: BoardCompanions, Quantity: 1, ScryfallID: "kaheera", Name: "Kaheera, the Orphanguard", Set: "iko", CollectorNumber: "224", Finish: "nonFoil"},
+			{Board: BoardMainboard, Quantity: 1, Name: "Ajani, Valiant Protector", Set: "aer", CollectorNumber: "185", Finish: "foil"},
+			{Board: BoardMainboard, Quantity: 10, Name: "Plains", Finish: "nonFoil"},
+			{Board: BoardSideboard, Quantity: 1, Name: "Swords to Plowshares", Set: "cmm", CollectorNumber: "50", Finish: "nonFoil", IsProxy: true},
+			{Board: BoardMaybeboard, Quantity: 1, Name: "Jetmir, Nexus of Revels", Set: "snc", CollectorNumber: "128", Finish: "etched"},
+		},
+	}
+	deck.ResolveLeaders()
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{
+			format: ExportArena,
+			want: `Commander
+1 Arahbo, Roar of the World (C20) 2
+
+Companion
+1 Kaheera, the Orphanguard (IKO) 224
+
+Deck
+1 Ajani, Valiant Protector (AER) 185
+10 Plains
+
+Sideboard
+1 Kaheera, the Orphanguard (IKO) 224
+1 Swords to Plowshares (CMM) 50
+`,
+		},
+		{
+			format: ExportMTGO,
+			want: `1 Ajani, Valiant Protector
+10 Plains
+
+1 Arahbo, Roar of the World
+1 Kaheera, the Orphanguard
+1 Kaheera, the Orphanguard
+1 Swords to Plowshares
+`,
+		},
+		{
+			format: ExportCSV,
+			want: `section,quantity,name,set,collector_number,finish,is_proxy,scryfall_id
+commanders,1,"Arahbo, Roar of the World",c20,2,nonFoil,false,arahbo
+companions,1,"Kaheera, the Orphanguard",iko,224,nonFoil,false,kaheera
+mainboard,1,"Ajani, Valiant Protector",aer,185,foil,false,
+mainboard,10,Plains,,,nonFoil,false,
+sideboard,1,"Kaheera, the Orphanguard",iko,224,nonFoil,false,kaheera
+sideboard,1,Swords to Plowshares,cmm,50,nonFoil,true,
+maybeboard,1,"Jetmir, Nexus of Revels",snc,128,etched,false,
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := ExportDeck(&buf, deck, tt.format); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, buf.String()); diff != "" {
+				t.Errorf("wrong export:\n%s", diff)
+			}
+		})
+	}
+
+	t.Run(ExportJSON, func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := ExportDeck(&buf, deck, ExportJSON); err != nil {
+			t.Fatal(err)
+		}
+		var got Deck
+		if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(deck.Cards, got.Cards); diff != "" {
+			t.Errorf("wrong cards in export:\n%s", diff)
+		}
+	})
+}
+
+func TestExportDeckOathbreaker(t *testing.T) {
+	deck := Deck{
+		Format: "oathbreaker",
+		Cards: []DeckCard{
+			{Board: BoardCommanders, Quantity: 1, ScryfallID: "tamiyo", Name: "Tamiyo, Compleated Sage", TypeLine: "Legendary Planeswalker — Tamiyo"},
+			{Board: BoardSignatureSpells, Quantity: 1, ScryfallID: "prologue", Name: "Prologue to Phyresis", TypeLine: "Instant"},
+			{Board: BoardMainboard, Quantity: 1, Name: "Island", TypeLine: "Basic Land — Island"},
+		},
+	}
+	deck.ResolveLeaders()
+
+	var buf bytes.Buffer
+	if err := ExportDeck(&buf, deck, ExportArena); err != nil {
+		t.Fatal(err)
+	}
+	want := "Commander\n1 Tamiyo, Compleated Sage\n1 Prologue to Phyresis\n\nDeck\n1 Island\n"
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Errorf("wrong export:\n%s", diff)
+	}
+
+	// Importing the list again should give the same boards back.
+	cards, err := ParseDecklist(buf.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range cards {
+		cards[i].TypeLine = deck.Cards[i].TypeLine
+	}
+	imported := Deck{Format: "oathbreaker", Cards: cards}
+	imported.ResolveLeaders()
+	for i := range cards {
+		if got, want := imported.Cards[i].Board, deck.Cards[i].Board; got != want {
+			t.Errorf("%s should be on %s after import but was on %s", cards[i].Name, want, got)
+		}
+	}
+
+	buf.Reset()
+	if err := ExportDeck(&buf, deck, ExportCSV); err != nil {
+		t.Fatal(err)
+	}
+	want = `section,quantity,name,set,collector_number,finish,is_proxy,scryfall_id
+oathbreakers,1,"Tamiyo, Compleated Sage",,,,false,tamiyo
+signature_spells,1,Prologue to Phyresis,,,,false,prologue
+mainboard,1,Island,,,,false,
+`
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Errorf("wrong export:\n%s", diff)
+	}
+}
+
+func TestExportDeckUnknownFormat(t *testing.T) {
+	var buf bytes.Buffer
+	if err := ExportDeck(&buf, Deck{}, "cockatrice"); !errors.Is(err, ErrUnknownExportFormat) {
+		t.Errorf("exporting to an unknown format should fail with ErrUnknownExportFormat but got %v", err)
+	}
+}
@@ -0,0 +1,589 @@
+package magic
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"github.com/jcbwlkr/deck-stats/internal/domains/users"
+)
+
+var (
+	ErrGameNotFound = errors.New("game not found")
+	ErrInvalidGame  = errors.New("invalid game")
+)
+
+// Values for Game.Result from the point of view of the user's deck.
+const (
+	ResultWin  = "win"
+	ResultLoss = "loss"
+	ResultDraw = "draw"
+)
+
+// Values for Game.EndReason. It is blank if not recorded.
+const (
+	EndCombat          = "combat"
+	EndCombo           = "combo"
+	EndCommanderDamage = "commander_damage"
+	EndConcession      = "concession"
+	EndOther           = "other"
+)
+
+var endReasons = []string{"", EndCombat, EndCombo, EndCommanderDamage, EndConcession, EndOther}
+
+// Game is the result of one game played with one of the user's decks.
+type Game struct {
+	ID        string    `db:"id" json:"id"`
+	UserID    string    `db:"user_id" json:"-"`
+	DeckID    string    `db:"deck_id" json:"deck_id"`
+	PlayedAt  time.Time `db:"played_at" json:"played_at"`
+	Format    string    `db:"format" json:"format"`
+	Result    string    `db:"result" json:"result"`
+	EndReason string    `db:"end_reason" json:"end_reason,omitempty"`
+	Turns     int       `db:"turns" json:"turns,omitempty"` // 0 if not recorded
+	Notes     string    `db:"notes" json:"notes"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+
+	Seats []Seat `db:"-" json:"seats"`
+}
+
+// Seat is one player in a game. Exactly one seat is the user's and plays the
+// game's deck. Other players' decks are either described in free text or
+// linked to one of the user's own decks such as when a friend borrows it.
+//
+// Position is the turn order starting at 1. EliminatedOrder is 1 for the first
+// player knocked out, 2 for the next and so on. Zero in any of these means it
+// wasn't recorded or, for eliminations, that the player survived.
+type Seat struct {
+	ID              string `db:"id" json:"-"`
+	GameID          string `db:"game_id" json:"-"`
+	Position        int    `db:"position" json:"position,omitempty"`
+	Player          string `db:"player" json:"player"`
+	You             bool   `db:"is_user" json:"you,omitempty"`
+	DeckID          string `db:"deck_id" json:"deck_id,omitempty"`
+	Deck            string `db:"deck" json:"deck,omitempty"`
+	Commander       string `db:"commander" json:"commander,omitempty"`
+	Won             bool   `db:"won" json:"won,omitempty"`
+	EliminatedOrder int    `db:"eliminated_order" json:"eliminated_order,omitempty"`
+	EliminatedTurn  int    `db:"eliminated_turn" json:"eliminated_turn,omitempty"`
+}
+
+// NewGame is what a user sends to record or edit a game. The user's own seat
+// may be left out of Seats for a quick log without turn order.
+type NewGame struct {
+	DeckID    string    `json:"deck_id"`
+	PlayedAt  time.Time `json:"played_at"`
+	Format    string    `json:"format"`
+	Seats     []Seat    `json:"seats"`
+	Result    string    `json:"result"`
+	EndReason string    `json:"end_reason"`
+	Turns     int       `json:"turns"`
+	Notes     string    `json:"notes"`
+}
+
+// validate checks the fields that don't need the database. It fills in the
+// user's seat if it was left out and marks it as the winner of a won game.
+func (ng *NewGame) validate() error {
+	switch ng.Result {
+	case ResultWin, ResultLoss, ResultDraw:
+	default:
+		return fmt.Errorf("%w: result must be %s, %s or %s", ErrInvalidGame, ResultWin, ResultLoss, ResultDraw)
+	}
+
+	if !slices.Contains(endReasons, ng.EndReason) {
+		return fmt.Errorf("%w: unknown end_reason %q", ErrInvalidGame, ng.EndReason)
+	}
+	if ng.DeckID == "" {
+		return fmt.Errorf("%w: deck_id is required", ErrInvalidGame)
+	}
+	if ng.Turns < 0 {
+		return fmt.Errorf("%w: turns can't be negative", ErrInvalidGame)
+	}
+
+	you := slices.IndexFunc(ng.Seats, func(s Seat) bool { return s.You })
+	if you < 0 {
+		ng.Seats = append([]Seat{{You: true}}, ng.Seats...)
+		you = 0
+	}
+	if ng.Seats[you].DeckID == "" {
+		ng.Seats[you].DeckID = ng.DeckID
+	}
+	if ng.Seats[you].DeckID != ng.DeckID {
+		return fmt.Errorf("%w: your seat must play deck_id", ErrInvalidGame)
+	}
+	if ng.Result == ResultWin {
+		ng.Seats[you].Won = true
+	}
+
+	var (
+		yours, winners int
+		positions      []int
+		eliminations   []int
+		decks          = map[string]bool{}
+	)
+	for _, s := range ng.Seats {
+		if s.DeckID != "" {
+			if decks[s.DeckID] {
+				return fmt.Errorf("%w: a deck can only be in one seat", ErrInvalidGame)
+			}
+			decks[s.DeckID] = true
+		}
+		if s.You {
+			yours++
+		} else if strings.TrimSpace(s.Player) == "" && s.DeckID == "" && strings.TrimSpace(s.Deck) == "" && strings.TrimSpace(s.Commander) == "" {
+			return fmt.Errorf("%w: seats need a player or a deck", ErrInvalidGame)
+		}
+		if s.Won {
+			winners++
+			if s.EliminatedOrder != 0 {
+				return fmt.Errorf("%w: the winner can't be eliminated", ErrInvalidGame)
+			}
+		}
+		if s.Position < 0 || s.EliminatedOrder < 0 || s.EliminatedTurn < 0 {
+			return fmt.Errorf("%w: seat numbers can't be negative", ErrInvalidGame)
+		}
+		if s.Position > 0 {
+			positions = append(positions, s.Position)
+		}
+		if s.EliminatedOrder > 0 {
+			eliminations = append(eliminations, s.EliminatedOrder)
+		}
+	}
+
+	switch {
+	case yours > 1:
+		return fmt.Errorf("%w: only one seat can be yours", ErrInvalidGame)
+	case winners > 1:
+		return fmt.Errorf("%w: only one seat can win", ErrInvalidGame)
+	case winners > 0 && ng.Result == ResultDraw:
+		return fmt.Errorf("%w: a drawn game has no winner", ErrInvalidGame)
+	case ng.Result != ResultWin && ng.Seats[you].Won:
+		return fmt.Errorf("%w: your seat can only win a won game", ErrInvalidGame)
+	}
+
+	// Turn order is either left out entirely or covers every seat.
+	if len(positions) > 0 && !isSequence(positions, len(ng.Seats)) {
+		return fmt.Errorf("%w: positions must number every seat from 1", ErrInvalidGame)
+	}
+	if len(eliminations) > 0 && !isSequence(eliminations, len(eliminations)) {
+		return fmt.Errorf("%w: eliminated_order must count up from 1", ErrInvalidGame)
+	}
+	if len(eliminations) >= len(ng.Seats) {
+		return fmt.Errorf("%w: someone has to survive", ErrInvalidGame)
+	}
+
+	return nil
+}
+
+// isSequence reports if nums holds exactly the numbers 1 through n.
+func isSequence(nums []int, n int) bool {
+	if len(nums) != n {
+		return false
+	}
+	sorted := slices.Sorted(slices.Values(nums))
+	for i, num := range sorted {
+		if num != i+1 {
+			return false
+		}
+	}
+	return true
+}
+
+// prepareGame validates the game and checks that every deck it links to
+// belongs to the user. The format defaults to the format of the user's deck.
+func (s *Service) prepareGame(ctx context.Context, user users.User, ng NewGame) (NewGame, error) {
+	if err := ng.validate(); err != nil {
+		return ng, err
+	}
+
+	format, err := s.deckFormat(ctx, user, ng.DeckID)
+	if err != nil {
+		return ng, err
+	}
+	if ng.Format == "" {
+		ng.Format = format
+	}
+
+	for i := range ng.Seats {
+		seat := &ng.Seats[i]
+		if seat.DeckID != "" && !seat.You {
+			if _, err := s.deckFormat(ctx, user, seat.DeckID); err != nil {
+				return ng, err
+			}
+		}
+		seat.Player = strings.TrimSpace(seat.Player)
+		seat.Deck = strings.TrimSpace(seat.Deck)
+		seat.Commander = strings.TrimSpace(seat.Commander)
+	}
+
+	if ng.PlayedAt.IsZero() {
+		ng.PlayedAt = time.Now()
+	}
+	ng.Notes = strings.TrimSpace(ng.Notes)
+
+	return ng, nil
+}
+
+// deckFormat looks up the format of one of the user's decks. It is how games
+// check that the decks they reference are owned by the user and not deleted.
+func (s *Service) deckFormat(ctx context.Context, user users.User, deckID string) (string, error) {
+
+	const q = `
+	SELECT format
+	FROM decks
+	WHERE id = $1
+		AND user_id = $2
+		AND deleted_at IS NULL`
+
+	var format string
+	if err := s.db.GetContext(ctx, &format, q, deckID, user.ID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", fmt.Errorf("%w: %w %q", ErrInvalidGame, ErrDeckNotFound, deckID)
+		}
+		return "", err
+	}
+
+	return format, nil
+}
+
+// CreateGame records a game played with one of the user's decks.
+func (s *Service) CreateGame(ctx context.Context, user users.User, ng NewGame) (Game, error) {
+	ng, err := s.prepareGame(ctx, user, ng)
+	if err != nil {
+		return Game{}, err
+	}
+
+	now := time.Now()
+	game := Game{
+		ID:        uuid.New().String(),
+		UserID:    user.ID,
+		DeckID:    ng.DeckID,
+		PlayedAt:  ng.PlayedAt,
+		Format:    ng.Format,
+		Result:    ng.Result,
+		EndReason: ng.EndReason,
+		Turns:     ng.Turns,
+		Notes:     ng.Notes,
+		CreatedAt: now,
+		UpdatedAt: now,
+		Seats:     ng.Seats,
+	}
+
+	const q = `
+	INSERT INTO games (
+		id,
+		user_id,
+		deck_id,
+		played_at,
+		format,
+		result,
+		end_reason,
+		turns,
+		notes,
+		created_at,
+		updated_at
+	) VALUES (
+		:id,
+		:user_id,
+		:deck_id,
+		:played_at,
+		:format,
+		:result,
+		:end_reason,
+		:turns,
+		:notes,
+		:created_at,
+		:updated_at
+	)`
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return Game{}, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.NamedExecContext(ctx, q, game); err != nil {
+		return Game{}, err
+	}
+
+	if err := replaceGameSeats(ctx, tx, game.ID, game.Seats); err != nil {
+		return Game{}, err
+	}
+
+	if err := recomputeRatings(ctx, tx, s.ratings, user.ID, game.PlayedAt); err != nil {
+		return Game{}, fmt.Errorf("could not update ratings: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return Game{}, err
+	}
+
+	return game, nil
+}
+
+// GetGames lists the user's games, newest first. If deckID is not blank only
+// games played with that deck are included.
+func (s *Service) GetGames(ctx context.Context, user users.User, deckID string) ([]Game, error) {
+
+	const q = `
+	SELECT
+		id,
+		user_id,
+		deck_id,
+		played_at,
+		format,
+		result,
+		end_reason,
+		turns,
+		notes,
+		created_at,
+		updated_at
+	FROM games
+	WHERE user_id = $1
+		AND ($2 = '' OR deck_id = $2)
+	ORDER BY played_at DESC`
+
+	games := []Game{}
+	if err := s.db.SelectContext(ctx, &games, q, user.ID, deckID); err != nil {
+		return nil, err
+	}
+
+	if err := addGameSeats(ctx, s.db, games); err != nil {
+		return nil, fmt.Errorf("could not load seats: %w", err)
+	}
+
+	return games, nil
+}
+
+// GetGame loads one of the user's games.
+func (s *Service) GetGame(ctx context.Context, user users.User, id string) (Game, error) {
+
+	const q = `
+	SELECT
+		id,
+		user_id,
+		deck_id,
+		played_at,
+		format,
+		result,
+		end_reason,
+		turns,
+		notes,
+		created_at,
+		updated_at
+	FROM games
+	WHERE id = $1
+		AND user_id = $2`
+
+	var game Game
+	if err := s.db.GetContext(ctx, &game, q, id, user.ID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Game{}, ErrGameNotFound
+		}
+		return Game{}, err
+	}
+
+	games := []Game{game}
+	if err := addGameSeats(ctx, s.db, games); err != nil {
+		return Game{}, fmt.Errorf("could not load seats: %w", err)
+	}
+
+	return games[0], nil
+}
+
+// UpdateGame replaces the details of one of the user's games.
+func (s *Service) UpdateGame(ctx context.Context, user users.User, id string, ng NewGame) (Game, error) {
+	game, err := s.GetGame(ctx, user, id)
+	if err != nil {
+		return Game{}, err
+	}
+	playedAt := game.PlayedAt
+
+	ng, err = s.prepareGame(ctx, user, ng)
+	if err != nil {
+		return Game{}, err
+	}
+
+	game.DeckID = ng.DeckID
+	game.PlayedAt = ng.PlayedAt
+	game.Format = ng.Format
+	game.Result = ng.Result
+	game.EndReason = ng.EndReason
+	game.Turns = ng.Turns
+	game.Notes = ng.Notes
+	game.Seats = ng.Seats
+	game.UpdatedAt = time.Now()
+
+	const q = `
+	UPDATE games SET
+		deck_id = :deck_id,
+		played_at = :played_at,
+		format = :format,
+		result = :result,
+		end_reason = :end_reason,
+		turns = :turns,
+		notes = :notes,
+		updated_at = :updated_at
+	WHERE id = :id
+		AND user_id = :user_id`
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return Game{}, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.NamedExecContext(ctx, q, game); err != nil {
+		return Game{}, err
+	}
+
+	if err := replaceGameSeats(ctx, tx, game.ID, game.Seats); err != nil {
+		return Game{}, err
+	}
+
+	// Ratings change from wherever the game was or now is, whichever is
+	// earlier.
+	since := game.PlayedAt
+	if playedAt.Before(since) {
+		since = playedAt
+	}
+	if err := recomputeRatings(ctx, tx, s.ratings, user.ID, since); err != nil {
+		return Game{}, fmt.Errorf("could not update ratings: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return Game{}, err
+	}
+
+	return game, nil
+}
+
+// DeleteGame removes one of the user's games.
+func (s *Service) DeleteGame(ctx context.Context, user users.User, id string) error {
+
+	const q = `
+	DELETE FROM games
+	WHERE id = $1
+		AND user_id = $2
+	RETURNING played_at`
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var playedAt time.Time
+	if err := tx.GetContext(ctx, &playedAt, q, id, user.ID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrGameNotFound
+		}
+		return err
+	}
+
+	if err := recomputeRatings(ctx, tx, s.ratings, user.ID, playedAt); err != nil {
+		return fmt.Errorf("could not update ratings: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// addGameSeats loads the seats of every game in one query.
+func addGameSeats(ctx context.Context, db sqlx.QueryerContext, games []Game) error {
+	if len(games) == 0 {
+		return nil
+	}
+
+	ids := make([]string, len(games))
+	for i, g := range games {
+		ids[i] = g.ID
+	}
+
+	const q = `
+	SELECT
+		id,
+		game_id,
+		position,
+		player,
+		is_user,
+		deck_id,
+		deck,
+		commander,
+		won,
+		eliminated_order,
+		eliminated_turn
+	FROM game_seats
+	WHERE game_id = ANY($1)
+	ORDER BY position, id`
+
+	seats := []Seat{}
+	if err := sqlx.SelectContext(ctx, db, &seats, q, pq.StringArray(ids)); err != nil {
+		return err
+	}
+
+	byGame := map[string][]Seat{}
+	for _, seat := range seats {
+		byGame[seat.GameID] = append(byGame[seat.GameID], seat)
+	}
+	for i := range games {
+		games[i].Seats = byGame[games[i].ID]
+		if games[i].Seats == nil {
+			games[i].Seats = []Seat{}
+		}
+	}
+
+	return nil
+}
+
+func replaceGameSeats(ctx context.Context, tx *sqlx.Tx, gameID string, seats []Seat) error {
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM game_seats WHERE game_id = $1`, gameID); err != nil {
+		return err
+	}
+
+	const q = `
+	INSERT INTO game_seats (
+		id,
+		game_id,
+		position,
+		player,
+		is_user,
+		deck_id,
+		deck,
+		commander,
+		won,
+		eliminated_order,
+		eliminated_turn
+	) VALUES (
+		:id,
+		:game_id,
+		:position,
+		:player,
+		:is_user,
+		:deck_id,
+		:deck,
+		:commander,
+		:won,
+		:eliminated_order,
+		:eliminated_turn
+	)`
+
+	for i := range seats {
+		seats[i].ID = uuid.New().String()
+		seats[i].GameID = gameID
+		if _, err := tx.NamedExecContext(ctx, q, seats[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,74 @@
+package magic
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewGameValidate(t *testing.T) {
+	pod := func() NewGame {
+		return NewGame{
+			DeckID:    "deck",
+			Result:    ResultLoss,
+			EndReason: EndCommanderDamage,
+			Turns:     9,
+			Seats: []Seat{
+				{Position: 1, Player: "Alex", Commander: "Atraxa, Praetors' Voice", EliminatedOrder: 2, EliminatedTurn: 8},
+				{Position: 2, You: true, EliminatedOrder: 3, EliminatedTurn: 9},
+				{Position: 3, Player: "Sam", DeckID: "other-deck", Won: true},
+				{Position: 4, Player: "Kim", Deck: "Krenko tokens", EliminatedOrder: 1, EliminatedTurn: 6},
+			},
+		}
+	}
+
+	ng := pod()
+	if err := ng.validate(); err != nil {
+		t.Errorf("pod should be valid but got %v", err)
+	}
+	if got, want := ng.Seats[1].DeckID, "deck"; got != want {
+		t.Errorf("your seat should play %q but plays %q", want, got)
+	}
+
+	// A quick log of a win with no seats gets a seat for the user.
+	quick := NewGame{DeckID: "deck", Result: ResultWin}
+	if err := quick.validate(); err != nil {
+		t.Errorf("quick game should be valid but got %v", err)
+	}
+	if diff := cmp.Diff([]Seat{{You: true, DeckID: "deck", Won: true}}, quick.Seats); diff != "" {
+		t.Errorf("quick game has wrong seats:\n%s", diff)
+	}
+
+	tests := []struct {
+		name string
+		edit func(ng *NewGame)
+	}{
+		{"no deck", func(ng *NewGame) { ng.DeckID = "" }},
+		{"bad result", func(ng *NewGame) { ng.Result = "won" }},
+		{"bad end reason", func(ng *NewGame) { ng.EndReason = "boredom" }},
+		{"negative turns", func(ng *NewGame) { ng.Turns = -1 }},
+		{"blank seat", func(ng *NewGame) { ng.Seats[0] = Seat{Position: 1, Player: " ", EliminatedOrder: 2} }},
+		{"two winners", func(ng *NewGame) { ng.Seats[0].Won = true; ng.Seats[0].EliminatedOrder = 0 }},
+		{"opponent won a win", func(ng *NewGame) { ng.Result = ResultWin; ng.Seats[1].EliminatedOrder = 0 }},
+		{"winner in a draw", func(ng *NewGame) { ng.Result = ResultDraw }},
+		{"two of you", func(ng *NewGame) { ng.Seats[0].You = true }},
+		{"your seat plays another deck", func(ng *NewGame) { ng.Seats[1].DeckID = "other-deck" }},
+		{"winner eliminated", func(ng *NewGame) { ng.Seats[2].EliminatedOrder = 4 }},
+		{"duplicate position", func(ng *NewGame) { ng.Seats[3].Position = 1 }},
+		{"missing position", func(ng *NewGame) { ng.Seats[3].Position = 0 }},
+		{"elimination gap", func(ng *NewGame) { ng.Seats[0].EliminatedOrder = 4 }},
+		{"deck in two seats", func(ng *NewGame) { ng.Seats[0].DeckID = "deck" }},
+		{"negative turn", func(ng *NewGame) { ng.Seats[0].EliminatedTurn = -1 }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ng := pod()
+			tt.edit(&ng)
+			if err := ng.validate(); !errors.Is(err, ErrInvalidGame) {
+				t.Errorf("game should fail with ErrInvalidGame but got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package magic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/jcbwlkr/deck-stats/internal/domains/users"
+	"github.com/jcbwlkr/deck-stats/internal/services"
+)
+
+var (
+	ErrDeckNameRequired = errors.New("deck name is required")
+)
+
+// CardLookup fills in the details of cards that are only known by name or by
+// set and collector number such as those read from a plain text decklist.
+type CardLookup interface {
+	LookupCards(ctx context.Context, cards []DeckCard) error
+}
+
+// CardsNotFoundError is returned by a CardLookup when some cards could not be
+// found.
+type CardsNotFoundError struct {
+	Names []string
+}
+
+func (e CardsNotFoundError) Error() string {
+	return fmt.Sprintf("cards not found: %s", strings.Join(e.Names, ", "))
+}
+
+// ImportDeck is a plain text decklist to be stored as a manual deck.
+type ImportDeck struct {
+	Name   string `json:"name"`
+	Format string `json:"format"`
+	List   string `json:"list"`
+}
+
+// ImportDeck parses a plain text decklist and stores it as one of the user's
+// decks. The leaders and color identity are worked out from the cards the same
+// way they are for decks synced from a service.
+func (s *Service) ImportDeck(ctx context.Context, user users.User, id ImportDeck) (Deck, error) {
+	if strings.TrimSpace(id.Name) == "" {
+		return Deck{}, ErrDeckNameRequired
+	}
+	if id.Format == "" {
+		id.Format = "commander"
+	}
+
+	cards, err := ParseDecklist(id.List)
+	if err != nil {
+		return Deck{}, err
+	}
+
+	if err := s.cards.LookupCards(ctx, cards); err != nil {
+		return Deck{}, err
+	}
+
+	now := time.Now()
+	deck := Deck{
+		ID:          uuid.New().String(),
+		UserID:      user.ID,
+		Service:     services.Manual,
+		ServiceID:   uuid.New().String(),
+		Name:        strings.TrimSpace(id.Name),
+		Format:      id.Format,
+		Archetypes:  []Archetype{},
+		UpdatedAt:   now,
+		RefreshedAt: now,
+		Cards:       cards,
+	}
+	deck.ResolveLeaders()
+
+	if err := s.InsertDeck(ctx, deck); err != nil {
+		return Deck{}, err
+	}
+
+	return deck, nil
+}
+
+// ResolveLeaders fills in the deck's leaders and color identity from its
+// cards. In oathbreaker decks the planeswalkers listed as commanders are the
+// oathbreakers and any other cards with them are signature spells.
+func (d *Deck) ResolveLeaders() {
+	oathbreaker := d.Format == "oathbreaker"
+
+	var commanderIdentity, deckIdentity ColorIdentity
+	for i := range d.Cards {
+		dc := &d.Cards[i]
+
+		if dc.Board == BoardCommanders && oathbreaker && !strings.Contains(dc.TypeLine, "Planeswalker") {
+			dc.Board = BoardSignatureSpells
+		}
+
+		if dc.InDeck() {
+			deckIdentity = append(deckIdentity, dc.ColorIdentity...)
+		}
+
+		card := Card{
+			ID:   dc.ScryfallID,
+			Name: dc.Name,
+		}
+
+		switch {
+		case dc.Board == BoardCompanions:
+			d.Leaders.Companion = &card
+
+		case dc.Board == BoardSignatureSpells:
+			d.Leaders.SignatureSpells = append(d.Leaders.SignatureSpells, card)
+
+		case dc.Board == BoardCommanders && oathbreaker:
+			d.Leaders.Oathbreakers = append(d.Leaders.Oathbreakers, card)
+			commanderIdentity = append(commanderIdentity, dc.ColorIdentity...)
+
+		case dc.Board == BoardCommanders:
+			d.Leaders.Commanders = append(d.Leaders.Commanders, card)
+			commanderIdentity = append(commanderIdentity, dc.ColorIdentity...)
+		}
+	}
+
+	if len(commanderIdentity) > 0 {
+		d.ColorIdentity = commanderIdentity.Normalize()
+	} else {
+		d.ColorIdentity = deckIdentity.Normalize()
+	}
+}
@@ -0,0 +1,86 @@
+package magic
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestResolveLeaders(t *testing.T) {
+	tests := []struct {
+		name         string
+		deck         Deck
+		wantLeaders  Leaders
+		wantIdentity ColorIdentity
+		wantBoards   []string
+	}{
+		{
+			name: "commander",
+			deck: Deck{
+				Format: "commander",
+				Cards: []DeckCard{
+					{Board: BoardCommanders, ScryfallID: "1", Name: "Arahbo, Roar of the World", TypeLine: "Legendary Creature — Cat Avatar", ColorIdentity: Selesnya},
+					{Board: BoardCompanions, ScryfallID: "2", Name: "Kaheera, the Orphanguard", TypeLine: "Creature — Cat Beast", ColorIdentity: Selesnya},
+					{Board: BoardMainboard, ScryfallID: "3", Name: "Sol Ring", TypeLine: "Artifact", ColorIdentity: Colorless},
+					{Board: BoardSideboard, ScryfallID: "4", Name: "Counterspell", TypeLine: "Instant", ColorIdentity: MonoBlue},
+				},
+			},
+			wantLeaders: Leaders{
+				Commanders: []Card{{ID: "1", Name: "Arahbo, Roar of the World"}},
+				Companion:  &Card{ID: "2", Name: "Kaheera, the Orphanguard"},
+			},
+			wantIdentity: Selesnya,
+			wantBoards:   []string{BoardCommanders, BoardCompanions, BoardMainboard, BoardSideboard},
+		},
+		{
+			name: "oathbreaker",
+			deck: Deck{
+				Format: "oathbreaker",
+				Cards: []DeckCard{
+					{Board: BoardCommanders, ScryfallID: "1", Name: "Tamiyo, Compleated Sage", TypeLine: "Legendary Planeswalker — Tamiyo", ColorIdentity: Simic},
+					{Board: BoardCommanders, ScryfallID: "2", Name: "Prologue to Phyresis", TypeLine: "Instant", ColorIdentity: MonoBlue},
+				},
+			},
+			wantLeaders: Leaders{
+				Oathbreakers:    []Card{{ID: "1", Name: "Tamiyo, Compleated Sage"}},
+				SignatureSpells: []Card{{ID: "2", Name: "Prologue to Phyresis"}},
+			},
+			wantIdentity: Simic,
+			wantBoards:   []string{BoardCommanders, BoardSignatureSpells},
+		},
+		{
+			name: "no commander",
+			deck: Deck{
+				Format: "modern",
+				Cards: []DeckCard{
+					{Board: BoardMainboard, Name: "Lightning Bolt", TypeLine: "Instant", ColorIdentity: MonoRed},
+					{Board: BoardMainboard, Name: "Counterspell", TypeLine: "Instant", ColorIdentity: MonoBlue},
+					{Board: BoardSideboard, Name: "Thoughtseize", TypeLine: "Sorcery", ColorIdentity: MonoBlack},
+				},
+			},
+			wantIdentity: Izzet,
+			wantBoards:   []string{BoardMainboard, BoardMainboard, BoardSideboard},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.deck.ResolveLeaders()
+
+			if diff := cmp.Diff(tt.wantLeaders, tt.deck.Leaders); diff != "" {
+				t.Errorf("wrong leaders:\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.wantIdentity, tt.deck.ColorIdentity); diff != "" {
+				t.Errorf("wrong color identity:\n%s", diff)
+			}
+
+			var boards []string
+			for _, c := range tt.deck.Cards {
+				boards = append(boards, c.Board)
+			}
+			if diff := cmp.Diff(tt.wantBoards, boards); diff != "" {
+				t.Errorf("wrong boards:\n%s", diff)
+			}
+		})
+	}
+}
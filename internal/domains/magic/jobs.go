@@ -0,0 +1,427 @@
+package magic
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/jcbwlkr/deck-stats/internal/domains/users"
+)
+
+// Values for RefreshJob.Status.
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobCompleted = "completed"
+	JobFailed    = "failed"
+)
+
+const (
+	// heartbeatInterval is how often a running job marks its account active.
+	heartbeatInterval = 10 * time.Second
+
+	// staleAfter is how long a running job can go without a heartbeat before
+	// another worker assumes it died and takes it over.
+	staleAfter = 6 * heartbeatInterval
+
+	// pollInterval is how long idle workers wait before checking for jobs.
+	pollInterval = 5 * time.Second
+
+	// enqueueAttempts is how many times to try queuing a job when the
+	// account's active job keeps finishing in the way.
+	enqueueAttempts = 3
+
+	defaultMaxAttempts = 5
+	baseBackoff        = 30 * time.Second
+	maxBackoff         = 30 * time.Minute
+)
+
+// RefreshJob is a request to refresh the decks of one account. Jobs are stored
+// in the database so they survive restarts.
+type RefreshJob struct {
+	ID          string    `db:"id" json:"id"`
+	AccountID   string    `db:"account_id" json:"account_id"`
+	UserID      string    `db:"user_id" json:"-"`
+	Status      string    `db:"status" json:"status"`
+	Attempts    int       `db:"attempts" json:"attempts"`
+	MaxAttempts int       `db:"max_attempts" json:"max_attempts"`
+	LastError   string    `db:"last_error" json:"last_error,omitempty"`
+	RunAt       time.Time `db:"run_at" json:"run_at"`
+	CreatedAt   time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt   time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// backoff is how long to wait before retrying a job that has failed the given
+// number of attempts.
+func backoff(attempts int) time.Duration {
+	d := baseBackoff
+	for i := 1; i < attempts; i++ {
+		d *= 2
+		if d >= maxBackoff {
+			return maxBackoff
+		}
+	}
+	return d
+}
+
+// RefreshDecks queues a refresh of the account's decks. If the account already
+// has a job queued or running that job is returned instead.
+func (s *Service) RefreshDecks(ctx context.Context, user users.User, account users.Account) (RefreshJob, error) {
+
+	const q = `
+	INSERT INTO refresh_jobs (
+		id,
+		account_id,
+		user_id,
+		status,
+		attempts,
+		max_attempts,
+		last_error,
+		run_at,
+		created_at,
+		updated_at
+	) VALUES (
+		:id,
+		:account_id,
+		:user_id,
+		:status,
+		:attempts,
+		:max_attempts,
+		:last_error,
+		:run_at,
+		:created_at,
+		:updated_at
+	)
+	ON CONFLICT (account_id) WHERE status IN ('queued', 'running') DO NOTHING`
+
+	now := time.Now()
+	job := RefreshJob{
+		ID:          uuid.New().String(),
+		AccountID:   account.ID,
+		UserID:      user.ID,
+		Status:      JobQueued,
+		MaxAttempts: defaultMaxAttempts,
+		RunAt:       now,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	// The active job can finish between the insert and looking it up. Try
+	// again when it does since there is then nothing in the way.
+	for attempt := 1; ; attempt++ {
+		res, err := s.db.NamedExecContext(ctx, q, job)
+		if err != nil {
+			return RefreshJob{}, err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return RefreshJob{}, err
+		}
+		if n > 0 {
+			break
+		}
+
+		active, err := s.getActiveJob(ctx, account.ID)
+		if errors.Is(err, sql.ErrNoRows) && attempt < enqueueAttempts {
+			continue
+		}
+		return active, err
+	}
+
+	// Let an idle worker know there is something to do.
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+
+	return job, nil
+}
+
+func (s *Service) getActiveJob(ctx context.Context, accountID string) (RefreshJob, error) {
+
+	const q = `
+	SELECT
+		id,
+		account_id,
+		user_id,
+		status,
+		attempts,
+		max_attempts,
+		last_error,
+		run_at,
+		created_at,
+		updated_at
+	FROM refresh_jobs
+	WHERE account_id = $1
+		AND status IN ('queued', 'running')`
+
+	var job RefreshJob
+	err := s.db.GetContext(ctx, &job, q, accountID)
+	return job, err
+}
+
+// StartWorkers launches n goroutines that process refresh jobs until the
+// service is shut down.
+func (s *Service) StartWorkers(n int) {
+	for i := 0; i < n; i++ {
+		s.wg.Add(1)
+		go func(worker int) {
+			defer s.wg.Done()
+			s.work(slog.With("worker", worker))
+		}(i)
+	}
+}
+
+func (s *Service) work(log *slog.Logger) {
+	for {
+		job, ok, err := s.claimJob(s.ctx)
+		if err != nil && s.ctx.Err() == nil {
+			log.Error("could not claim refresh job", "error", err)
+		}
+		if ok {
+			s.runJob(log, job)
+			continue
+		}
+
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-s.wake:
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// claimJob takes the next job that is due or whose worker stopped sending
+// heartbeats. SKIP LOCKED lets many workers claim jobs at once without
+// handing the same job to two of them.
+func (s *Service) claimJob(ctx context.Context) (RefreshJob, bool, error) {
+
+	const q = `
+	SELECT
+		j.id,
+		j.account_id,
+		j.user_id,
+		j.status,
+		j.attempts,
+		j.max_attempts,
+		j.last_error,
+		j.run_at,
+		j.created_at,
+		j.updated_at
+	FROM refresh_jobs j
+	JOIN user_accounts a ON a.id = j.account_id
+	WHERE (j.status = 'queued' AND j.run_at <= $1)
+		OR (j.status = 'running' AND (a.refresh_active_at IS NULL OR a.refresh_active_at < $2))
+	ORDER BY j.run_at
+	LIMIT 1
+	FOR UPDATE OF j SKIP LOCKED`
+
+	const claim = `
+	UPDATE refresh_jobs SET
+		status = :status,
+		attempts = :attempts,
+		updated_at = :updated_at
+	WHERE id = :id`
+
+	// The heartbeat lives on the account. It is written in the same transaction
+	// so the job is never running without a fresh heartbeat.
+	const heartbeat = `
+	UPDATE user_accounts SET
+		refresh_started_at = $2,
+		refresh_active_at = $2,
+		refresh_completed_at = NULL,
+		refresh_status = $3
+	WHERE id = $1`
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return RefreshJob{}, false, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+
+	var job RefreshJob
+	if err := tx.GetContext(ctx, &job, q, now, now.Add(-staleAfter)); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return RefreshJob{}, false, nil
+		}
+		return RefreshJob{}, false, err
+	}
+
+	job.Status = JobRunning
+	job.Attempts++
+	job.UpdatedAt = now
+
+	if _, err := tx.NamedExecContext(ctx, claim, job); err != nil {
+		return RefreshJob{}, false, err
+	}
+	if _, err := tx.ExecContext(ctx, heartbeat, job.AccountID, now, users.RefreshStatusPending); err != nil {
+		return RefreshJob{}, false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return RefreshJob{}, false, err
+	}
+
+	return job, true, nil
+}
+
+// runJob refreshes the job's account while keeping its heartbeat fresh, then
+// records the outcome on both the job and the account.
+func (s *Service) runJob(logger *slog.Logger, job RefreshJob) {
+
+	ctx := s.ctx
+
+	// Status updates must still be written after ctx is canceled so we can
+	// record that the refresh was interrupted.
+	statusCtx := context.WithoutCancel(ctx)
+
+	log := logger.With("job", job.ID, "user", job.UserID, "attempt", job.Attempts)
+
+	account, err := s.us.GetAccount(statusCtx, job.AccountID, job.UserID)
+	if err != nil {
+		s.finishJob(statusCtx, log, job, nil, RefreshTotals{}, fmt.Errorf("could not get account: %w", err))
+		return
+	}
+	log = log.With("service", account.Service)
+
+	now := time.Now()
+	account.RefreshStartedAt = &now
+	account.RefreshActiveAt = &now
+	account.RefreshCompletedAt = nil
+	account.RefreshStatus = users.RefreshStatusPending
+
+	// Jobs taken over from a dead worker may have used up their attempts.
+	if job.Attempts > job.MaxAttempts {
+		job.Status = JobFailed
+		s.finishJob(statusCtx, log, job, &account, RefreshTotals{}, errors.New("too many attempts"))
+		return
+	}
+
+	// Goroutine that periodically marks the account as still refreshing.
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	go func(account users.Account) {
+		defer close(stopped)
+
+		tick := time.NewTicker(heartbeatInterval)
+		defer tick.Stop()
+
+		for {
+			select {
+			case now := <-tick.C:
+				log.InfoContext(ctx, "service still refreshing")
+				account.RefreshActiveAt = &now
+				if err := s.us.UpdateAccount(statusCtx, account); err != nil {
+					log.ErrorContext(ctx, "could not update refresh heartbeat", "error", err)
+				}
+			case <-stop:
+				return
+			}
+		}
+	}(account)
+
+	s.events.publish(RefreshEvent{Type: EventStarted, AccountID: account.ID})
+
+	var totals RefreshTotals
+	user := users.User{ID: job.UserID}
+	if src, ok := s.sources[account.Service]; ok {
+		totals, err = s.refreshSource(ctx, log, src, user, account)
+	} else {
+		err = fmt.Errorf("%w %q", ErrUnknownService, account.Service)
+	}
+
+	close(stop)
+	<-stopped
+
+	s.finishJob(statusCtx, log, job, &account, totals, err)
+}
+
+// finishJob records the result of a job. Failed jobs are retried later until
+// they run out of attempts. Jobs interrupted by shutdown are put back in the
+// queue without using up an attempt.
+func (s *Service) finishJob(ctx context.Context, log *slog.Logger, job RefreshJob, account *users.Account, totals RefreshTotals, err error) {
+
+	now := time.Now()
+	job.UpdatedAt = now
+
+	ev := RefreshEvent{AccountID: job.AccountID, Totals: &totals}
+	if err != nil {
+		ev.Error = err.Error()
+	}
+
+	var accountStatus string
+	switch {
+	case job.Status == JobFailed:
+		log.ErrorContext(ctx, "giving up on refresh", "error", err)
+		job.LastError = err.Error()
+		accountStatus = users.RefreshStatusFailed + err.Error()
+		ev.Type = EventError
+
+	case err != nil && s.ctx.Err() != nil:
+		log.WarnContext(ctx, "refresh interrupted", "error", err)
+		job.Status = JobQueued
+		job.Attempts--
+		job.RunAt = now
+		accountStatus = users.RefreshStatusInterrupted
+		ev.Type = EventInterrupted
+
+	case err != nil:
+		job.LastError = err.Error()
+		accountStatus = users.RefreshStatusFailed + err.Error()
+		ev.Type = EventError
+		if job.Attempts >= job.MaxAttempts {
+			log.ErrorContext(ctx, "failed to refresh, giving up", "error", err)
+			job.Status = JobFailed
+		} else {
+			wait := backoff(job.Attempts)
+			log.ErrorContext(ctx, "failed to refresh, will retry", "error", err, "retry_in", wait)
+			job.Status = JobQueued
+			job.RunAt = now.Add(wait)
+			ev.Retrying = true
+		}
+
+	default:
+		log.InfoContext(ctx, "refresh complete")
+		job.Status = JobCompleted
+		job.LastError = ""
+		accountStatus = users.RefreshStatusCompleted
+		ev.Type = EventCompleted
+	}
+
+	s.events.publish(ev)
+
+	if err := s.updateJob(ctx, job); err != nil {
+		log.ErrorContext(ctx, "could not update refresh job", "error", err)
+	}
+
+	if account == nil {
+		return
+	}
+	account.RefreshStatus = accountStatus
+	account.RefreshCompletedAt = &now
+	if err := s.us.UpdateAccount(ctx, *account); err != nil {
+		log.ErrorContext(ctx, "could not update account refresh status", "error", err)
+	}
+}
+
+func (s *Service) updateJob(ctx context.Context, job RefreshJob) error {
+	const q = `
+	UPDATE refresh_jobs SET
+		status = :status,
+		attempts = :attempts,
+		last_error = :last_error,
+		run_at = :run_at,
+		updated_at = :updated_at
+	WHERE id = :id`
+
+	_, err := s.db.NamedExecContext(ctx, q, job)
+	return err
+}
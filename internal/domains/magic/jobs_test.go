@@ -0,0 +1,26 @@
+package magic
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{6, 16 * time.Minute},
+		{7, 30 * time.Minute},
+		{50, 30 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := backoff(tt.attempts); got != tt.want {
+			t.Errorf("backoff after %d attempts should be %v but was %v", tt.attempts, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,269 @@
+package magic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Statuses a card can have in a format.
+const (
+	LegalityLegal      = "legal"
+	LegalityNotLegal   = "not_legal"
+	LegalityBanned     = "banned"
+	LegalityRestricted = "restricted"
+)
+
+// Rules a deck can break.
+const (
+	RuleLegality = "legality"
+)
+
+const (
+	// legalityInterval is how old a card's legalities can get before they are
+	// fetched again.
+	legalityInterval = 24 * time.Hour
+
+	// legalityBatch is the most cards checked in one pass.
+	legalityBatch = 750
+
+	// legalityPoll is how long to wait between passes once every card is up
+	// to date.
+	legalityPoll = time.Hour
+)
+
+// legalityFormats maps deck formats to the key their legality is stored under
+// for the few formats where the two names differ.
+var legalityFormats = map[string]string{
+	"historicbrawl":  "brawl",
+	"futurestandard": "future",
+	"duelcommander":  "duel",
+}
+
+// legalityFormat is the key of the format in a card's Legalities.
+func legalityFormat(format string) string {
+	if key, ok := legalityFormats[format]; ok {
+		return key
+	}
+	return format
+}
+
+// Violation is one way a deck breaks the rules of its format.
+type Violation struct {
+	Rule    string `json:"rule"`
+	Card    string `json:"card,omitempty"`
+	Board   string `json:"board,omitempty"`
+	Message string `json:"message"`
+}
+
+// DeckValidation is the result of checking a deck against its format.
+// Unchecked lists cards we have no legality data for in the format so they
+// couldn't be checked.
+type DeckValidation struct {
+	DeckID     string      `json:"deck_id"`
+	Format     string      `json:"format"`
+	Valid      bool        `json:"valid"`
+	Violations []Violation `json:"violations"`
+	Unchecked  []string    `json:"unchecked"`
+}
+
+// ValidateDeck checks the deck against the rules of its format. Every deck is
+// checked for card legality and Commander decks are also checked against the
+// Commander construction rules.
+func ValidateDeck(deck Deck) DeckValidation {
+	v := DeckValidation{
+		DeckID:     deck.ID,
+		Format:     deck.Format,
+		Violations: []Violation{},
+		Unchecked:  []string{},
+	}
+
+	violations, unchecked := checkLegality(deck)
+	v.Violations = append(v.Violations, violations...)
+	v.Unchecked = append(v.Unchecked, unchecked...)
+
+	if slices.Contains(commanderFormats, deck.Format) {
+		v.Violations = append(v.Violations, CheckCommander(deck)...)
+	}
+
+	v.Valid = len(v.Violations) == 0
+	return v
+}
+
+// checkLegality finds the cards that aren't legal in the deck's format. Cards
+// on the maybeboard aren't part of the deck so they are skipped. Restricted
+// cards may only be played as a single copy across every other board.
+func checkLegality(deck Deck) ([]Violation, []string) {
+	key := legalityFormat(deck.Format)
+
+	var (
+		violations []Violation
+		unchecked  []string
+		names      []string
+	)
+	quantities := map[string]int{}
+	cards := map[string]DeckCard{}
+
+	for _, c := range deck.Cards {
+		if c.Board == BoardMaybeboard {
+			continue
+		}
+		if _, ok := cards[c.Name]; !ok {
+			names = append(names, c.Name)
+			cards[c.Name] = c
+		}
+		quantities[c.Name] += c.Quantity
+	}
+
+	for _, name := range names {
+		c := cards[name]
+		status, ok := c.Legalities[key]
+		if !ok {
+			unchecked = append(unchecked, name)
+			continue
+		}
+
+		var message string
+		switch {
+		case status == LegalityNotLegal:
+			message = fmt.Sprintf("%s is not legal in %s", name, deck.Format)
+		case status == LegalityBanned:
+			message = fmt.Sprintf("%s is banned in %s", name, deck.Format)
+		case status == LegalityRestricted && quantities[name] > 1:
+			message = fmt.Sprintf("%s is restricted in %s but the deck has %d", name, deck.Format, quantities[name])
+		default:
+			continue
+		}
+
+		violations = append(violations, Violation{
+			Rule:    RuleLegality,
+			Card:    name,
+			Board:   c.Board,
+			Message: message,
+		})
+	}
+
+	return violations, unchecked
+}
+
+// StartLegalityRefresh launches a goroutine that keeps the legalities of every
+// card in a deck up to date until the service is shut down. Cards are checked
+// whether or not their deck changes so bans are noticed.
+func (s *Service) StartLegalityRefresh() {
+	if s.cards == nil {
+		return
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		log := slog.With("worker", "legalities")
+		for {
+			n, err := s.RefreshLegalities(s.ctx)
+			if err != nil && s.ctx.Err() == nil {
+				log.Error("could not refresh legalities", "error", err)
+			}
+			if n > 0 {
+				log.Info("refreshed legalities", "cards", n)
+			}
+			if err == nil && n == legalityBatch {
+				continue
+			}
+
+			select {
+			case <-s.ctx.Done():
+				return
+			case <-time.After(legalityPoll):
+			}
+		}
+	}()
+}
+
+// RefreshLegalities fetches the current legalities of the cards that were
+// never checked or were last checked too long ago. It returns how many cards
+// were checked. Cards that can't be found keep the legalities they have.
+func (s *Service) RefreshLegalities(ctx context.Context) (int, error) {
+
+	const q = `
+	SELECT DISTINCT ON (c.scryfall_id)
+		c.scryfall_id,
+		c.name,
+		c.set_code,
+		c.collector_number,
+		COALESCE(l.legalities, c.legalities) AS legalities
+	FROM deck_cards c
+	LEFT JOIN card_legalities l ON l.scryfall_id = c.scryfall_id
+	WHERE c.scryfall_id <> ''
+		AND (l.checked_at IS NULL OR l.checked_at < $1)
+	ORDER BY c.scryfall_id
+	LIMIT $2`
+
+	now := time.Now()
+
+	cards := []DeckCard{}
+	if err := s.db.SelectContext(ctx, &cards, q, now.Add(-legalityInterval), legalityBatch); err != nil {
+		return 0, err
+	}
+	if len(cards) == 0 {
+		return 0, nil
+	}
+
+	// A lookup by name can land on another printing so keep the ids asked for.
+	ids := make([]string, len(cards))
+	for i, c := range cards {
+		ids[i] = c.ScryfallID
+	}
+
+	if err := s.cards.LookupCards(ctx, cards); err != nil {
+		var notFound CardsNotFoundError
+		if !errors.As(err, &notFound) {
+			return 0, fmt.Errorf("could not look up cards: %w", err)
+		}
+		slog.WarnContext(ctx, "cards not found checking legalities", "names", notFound.Names)
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	for i, c := range cards {
+		if err := storeLegalities(ctx, tx, ids[i], c.Legalities, now); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return len(cards), nil
+}
+
+// storeLegalities records the legalities of a printing as of checkedAt.
+func storeLegalities(ctx context.Context, tx *sqlx.Tx, scryfallID string, legalities Legalities, checkedAt time.Time) error {
+
+	const q = `
+	INSERT INTO card_legalities (
+		scryfall_id,
+		legalities,
+		checked_at
+	) VALUES (
+		$1,
+		$2,
+		$3
+	)
+	ON CONFLICT (scryfall_id) DO UPDATE SET
+		legalities = EXCLUDED.legalities,
+		checked_at = EXCLUDED.checked_at`
+
+	_, err := tx.ExecContext(ctx, q, scryfallID, legalities, checkedAt)
+	return err
+}
@@ -0,0 +1,56 @@
+package magic
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestValidateDeckLegality(t *testing.T) {
+	legal := Legalities{"vintage": LegalityLegal, "legacy": LegalityLegal}
+
+	deck := Deck{
+		ID:     "deck",
+		Format: "vintage",
+		Cards: []DeckCard{
+			{Board: BoardMainboard, Quantity: 4, Name: "Brainstorm", Legalities: legal},
+			{Board: BoardMainboard, Quantity: 1, Name: "Sol Ring", Legalities: Legalities{"vintage": LegalityRestricted}},
+			{Board: BoardMainboard, Quantity: 1, Name: "Ancestral Recall", Legalities: Legalities{"vintage": LegalityRestricted}},
+			{Board: BoardSideboard, Quantity: 1, Name: "Ancestral Recall", Legalities: Legalities{"vintage": LegalityRestricted}},
+			{Board: BoardMainboard, Quantity: 1, Name: "Shahrazad", Legalities: Legalities{"vintage": LegalityBanned}},
+			{Board: BoardSideboard, Quantity: 1, Name: "Sorcerous Spyglass", Legalities: Legalities{"vintage": LegalityNotLegal}},
+			{Board: BoardMainboard, Quantity: 1, Name: "Homebrew"},
+			{Board: BoardMaybeboard, Quantity: 1, Name: "Chaos Orb", Legalities: Legalities{"vintage": LegalityBanned}},
+		},
+	}
+
+	want := DeckValidation{
+		DeckID: "deck",
+		Format: "vintage",
+		Valid:  false,
+		Violations: []Violation{
+			{Rule: RuleLegality, Card: "Ancestral Recall", Board: BoardMainboard, Message: "Ancestral Recall is restricted in vintage but the deck has 2"},
+			{Rule: RuleLegality, Card: "Shahrazad", Board: BoardMainboard, Message: "Shahrazad is banned in vintage"},
+			{Rule: RuleLegality, Card: "Sorcerous Spyglass", Board: BoardSideboard, Message: "Sorcerous Spyglass is not legal in vintage"},
+		},
+		Unchecked: []string{"Homebrew"},
+	}
+
+	if diff := cmp.Diff(want, ValidateDeck(deck)); diff != "" {
+		t.Errorf("wrong validation:\n%s", diff)
+	}
+}
+
+func TestValidateDeckLegalityFormatKey(t *testing.T) {
+	deck := Deck{
+		Format: "historicbrawl",
+		Cards: []DeckCard{
+			{Board: BoardCommanders, Quantity: 1, Name: "Ajani, Valiant Protector", Legalities: Legalities{"brawl": LegalityLegal}},
+		},
+	}
+
+	got := ValidateDeck(deck)
+	if !got.Valid || len(got.Unchecked) != 0 {
+		t.Errorf("historic brawl deck should be checked against brawl legality: %+v", got)
+	}
+}
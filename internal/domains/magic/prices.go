@@ -0,0 +1,208 @@
+package magic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/jcbwlkr/deck-stats/internal/domains/users"
+)
+
+var (
+	ErrUnknownVendor = errors.New("unknown vendor")
+)
+
+// Vendors we keep prices for. usd, eur and tix are Scryfall's prices (TCG
+// Player, Cardmarket and Cardhoarder) and the rest are stores: Card Kingdom,
+// CoolStuffInc, CardTrader and Star City Games.
+var Vendors = []string{"usd", "eur", "tix", "ck", "csi", "ct", "scg"}
+
+// DefaultVendor is used when no vendor is asked for.
+const DefaultVendor = "usd"
+
+// priceInterval is how often prices are captured for decks that haven't
+// changed. Decks that have changed get prices on every refresh.
+const priceInterval = 24 * time.Hour
+
+// Price is what one vendor charges for a card in one finish.
+type Price struct {
+	Vendor string  `json:"vendor"`
+	Finish string  `json:"finish"`
+	Amount float64 `json:"amount"`
+}
+
+// CardPrice is a price in the price history.
+type CardPrice struct {
+	ScryfallID string    `db:"scryfall_id" json:"scryfall_id"`
+	Vendor     string    `db:"vendor" json:"vendor"`
+	Finish     string    `db:"finish" json:"finish"`
+	Price      float64   `db:"price" json:"price"`
+	CapturedOn time.Time `db:"captured_on" json:"captured_on"`
+}
+
+// DeckValue is the value of a deck's current cards over time at one vendor.
+type DeckValue struct {
+	DeckID  string       `json:"deck_id"`
+	Vendor  string       `json:"vendor"`
+	History []ValuePoint `json:"history"`
+}
+
+// ValuePoint is the value of a deck on one day. Missing counts the cards that
+// had no price for their finish yet so they weren't included.
+type ValuePoint struct {
+	Date    time.Time `json:"date"`
+	Value   float64   `json:"value"`
+	Missing int       `json:"missing"`
+}
+
+// valued reports if the card counts towards the deck's value. Maybeboard cards
+// aren't part of the deck and proxies aren't worth anything.
+func (c DeckCard) valued() bool {
+	return c.Board != BoardMaybeboard && !c.IsProxy
+}
+
+// ValueHistory works out the value of the cards on every day there are prices.
+// Prices carry forward so a card without a price on some day is valued at its
+// last known price. prices must be sorted by CapturedOn.
+func ValueHistory(cards []DeckCard, prices []CardPrice) []ValuePoint {
+	type key struct{ id, finish string }
+
+	latest := map[key]float64{}
+	history := []ValuePoint{}
+
+	value := func(day time.Time) ValuePoint {
+		p := ValuePoint{Date: day}
+		for _, c := range cards {
+			if !c.valued() {
+				continue
+			}
+			price, ok := latest[key{c.ScryfallID, c.Finish}]
+			if !ok {
+				p.Missing += c.Quantity
+				continue
+			}
+			p.Value += price * float64(c.Quantity)
+		}
+		p.Value = math.Round(p.Value*100) / 100
+		return p
+	}
+
+	for i, price := range prices {
+		latest[key{price.ScryfallID, price.Finish}] = price.Price
+
+		last := i == len(prices)-1
+		if last || !prices[i+1].CapturedOn.Equal(price.CapturedOn) {
+			history = append(history, value(price.CapturedOn))
+		}
+	}
+
+	return history
+}
+
+// GetDeckValue builds the value history of one of the user's decks at a
+// vendor.
+func (s *Service) GetDeckValue(ctx context.Context, user users.User, deckID, vendor string) (DeckValue, error) {
+	if vendor == "" {
+		vendor = DefaultVendor
+	}
+	if !slices.Contains(Vendors, vendor) {
+		return DeckValue{}, fmt.Errorf("%w %q", ErrUnknownVendor, vendor)
+	}
+
+	deck, err := s.GetDeck(ctx, user, deckID)
+	if err != nil {
+		return DeckValue{}, err
+	}
+
+	const q = `
+	SELECT
+		scryfall_id,
+		vendor,
+		finish,
+		price,
+		captured_on
+	FROM card_prices
+	WHERE vendor = $2
+		AND scryfall_id IN (SELECT scryfall_id FROM deck_cards WHERE deck_id = $1)
+	ORDER BY captured_on`
+
+	prices := []CardPrice{}
+	if err := s.db.SelectContext(ctx, &prices, q, deck.ID, vendor); err != nil {
+		return DeckValue{}, err
+	}
+
+	return DeckValue{
+		DeckID:  deck.ID,
+		Vendor:  vendor,
+		History: ValueHistory(deck.Cards, prices),
+	}, nil
+}
+
+// CapturePrices records today's prices for the cards. Capturing again on the
+// same day replaces that day's prices.
+func (s *Service) CapturePrices(ctx context.Context, cards []DeckCard) error {
+
+	const q = `
+	INSERT INTO card_prices (
+		scryfall_id,
+		vendor,
+		finish,
+		price,
+		captured_on
+	) VALUES (
+		:scryfall_id,
+		:vendor,
+		:finish,
+		:price,
+		:captured_on
+	)
+	ON CONFLICT (scryfall_id, vendor, finish, captured_on) DO UPDATE SET
+		price = EXCLUDED.price`
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareNamedContext(ctx, q)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, c := range cards {
+		for _, p := range c.Prices {
+			cp := CardPrice{
+				ScryfallID: c.ScryfallID,
+				Vendor:     p.Vendor,
+				Finish:     p.Finish,
+				Price:      p.Amount,
+				CapturedOn: today,
+			}
+			if _, err := stmt.ExecContext(ctx, cp); err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+// pricesDue reports if the deck's prices haven't been fetched recently.
+func (d Deck) pricesDue(now time.Time) bool {
+	return d.PricesCheckedAt == nil || now.Sub(*d.PricesCheckedAt) >= priceInterval
+}
+
+// markPricesChecked records that prices were fetched for the deck.
+func (s *Service) markPricesChecked(ctx context.Context, deckID string, at time.Time) error {
+	const q = `UPDATE decks SET prices_checked_at = $2 WHERE id = $1`
+
+	_, err := s.db.ExecContext(ctx, q, deckID, at)
+	return err
+}
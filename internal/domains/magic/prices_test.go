@@ -0,0 +1,71 @@
+package magic
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestValueHistory(t *testing.T) {
+	day1 := time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC)
+	day2 := day1.AddDate(0, 0, 1)
+	day3 := day1.AddDate(0, 0, 2)
+
+	cards := []DeckCard{
+		{Board: BoardCommanders, Quantity: 1, ScryfallID: "arahbo", Finish: "foil"},
+		{Board: BoardMainboard, Quantity: 2, ScryfallID: "ring", Finish: "nonFoil"},
+		{Board: BoardMainboard, Quantity: 1, ScryfallID: "vanguard", Finish: "etched"},
+		{Board: BoardMainboard, Quantity: 1, ScryfallID: "proxy", Finish: "nonFoil", IsProxy: true},
+		{Board: BoardMaybeboard, Quantity: 1, ScryfallID: "maybe", Finish: "nonFoil"},
+	}
+
+	prices := []CardPrice{
+		{ScryfallID: "arahbo", Finish: "nonFoil", Price: 1, CapturedOn: day1},
+		{ScryfallID: "arahbo", Finish: "foil", Price: 5, CapturedOn: day1},
+		{ScryfallID: "ring", Finish: "nonFoil", Price: 1.5, CapturedOn: day1},
+		{ScryfallID: "proxy", Finish: "nonFoil", Price: 100, CapturedOn: day1},
+		{ScryfallID: "maybe", Finish: "nonFoil", Price: 100, CapturedOn: day1},
+
+		// The ring price is carried forward from day 1.
+		{ScryfallID: "arahbo", Finish: "foil", Price: 6, CapturedOn: day2},
+		{ScryfallID: "vanguard", Finish: "etched", Price: 0.13, CapturedOn: day2},
+
+		{ScryfallID: "ring", Finish: "nonFoil", Price: 1.25, CapturedOn: day3},
+	}
+
+	want := []ValuePoint{
+		{Date: day1, Value: 8, Missing: 1},
+		{Date: day2, Value: 9.13},
+		{Date: day3, Value: 8.63},
+	}
+
+	if diff := cmp.Diff(want, ValueHistory(cards, prices)); diff != "" {
+		t.Errorf("wrong value history:\n%s", diff)
+	}
+}
+
+func TestPricesDue(t *testing.T) {
+	now := time.Date(2024, 11, 2, 12, 0, 0, 0, time.UTC)
+	recent := now.Add(-time.Hour)
+	old := now.Add(-priceInterval)
+
+	tests := []struct {
+		name    string
+		checked *time.Time
+		want    bool
+	}{
+		{name: "never checked", checked: nil, want: true},
+		{name: "checked recently", checked: &recent, want: false},
+		{name: "checked a day ago", checked: &old, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := Deck{PricesCheckedAt: tt.checked}
+			if got := d.pricesDue(now); got != tt.want {
+				t.Errorf("pricesDue should be %t but was %t", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,371 @@
+package magic
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/jcbwlkr/deck-stats/internal/domains/users"
+)
+
+// RatingConfig holds the parameters of the rating engine. Ratings have to be
+// recomputed when these change.
+type RatingConfig struct {
+	Initial float64 // Rating of new decks and of players without a linked deck
+	K       float64 // Most a rating can move in one game
+}
+
+// DefaultRatingConfig uses the usual chess values.
+var DefaultRatingConfig = RatingConfig{
+	Initial: 1500,
+	K:       32,
+}
+
+// eloScale is the rating difference at which the stronger side is expected to
+// win ten times as often.
+const eloScale = 400
+
+// DeckRating is where a deck stands on the leaderboard.
+type DeckRating struct {
+	DeckID    string    `db:"deck_id" json:"deck_id"`
+	UserID    string    `db:"user_id" json:"-"`
+	DeckName  string    `db:"deck_name" json:"deck_name"`
+	Rating    float64   `db:"rating" json:"rating"`
+	Games     int       `db:"games" json:"games"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// RatingChange is how one game moved the rating of one deck.
+type RatingChange struct {
+	DeckID       string    `db:"deck_id" json:"deck_id"`
+	GameID       string    `db:"game_id" json:"game_id"`
+	UserID       string    `db:"user_id" json:"-"`
+	PlayedAt     time.Time `db:"played_at" json:"played_at"`
+	RatingBefore float64   `db:"rating_before" json:"rating_before"`
+	RatingAfter  float64   `db:"rating_after" json:"rating_after"`
+}
+
+// standing scores how well a seat did so seats can be compared. Higher is
+// better. The winner beats everyone, survivors beat anyone eliminated and
+// players eliminated later beat those eliminated earlier. When the user lost
+// without being eliminated they lost to the other survivors.
+func (g Game) standing(s Seat) int {
+	switch {
+	case s.Won:
+		return 1000
+	case s.EliminatedOrder > 0:
+		return s.EliminatedOrder
+	case s.You && g.Result == ResultLoss:
+		return 499
+	}
+	return 500
+}
+
+// RateGames runs every game through a multiplayer Elo and returns the final
+// rating of every deck along with the history of changes. Games must be in
+// the order they were played.
+//
+// A free for all game is treated as a match between every pair of seats with
+// each deck's change averaged over its opponents. Seats without a linked deck
+// play at the initial rating and aren't rated themselves. Games with only the
+// user's seat are played against one such unknown opponent.
+func RateGames(cfg RatingConfig, games []Game) ([]DeckRating, []RatingChange) {
+	return RateGamesFrom(cfg, nil, games)
+}
+
+// RateGamesFrom is RateGames starting from the ratings decks had before the
+// first game instead of from scratch. Decks in start that play no games keep
+// their rating.
+func RateGamesFrom(cfg RatingConfig, start []DeckRating, games []Game) ([]DeckRating, []RatingChange) {
+	ratings := map[string]*DeckRating{}
+	changes := []RatingChange{}
+
+	for _, r := range start {
+		ratings[r.DeckID] = &r
+	}
+
+	for _, g := range games {
+		seats := g.Seats
+		if len(seats) == 1 {
+			opponent := Seat{Player: "unknown"}
+			switch g.Result {
+			case ResultWin:
+				opponent.EliminatedOrder = 1
+			case ResultLoss:
+				opponent.Won = true
+			}
+			seats = append(slices.Clone(seats), opponent)
+		}
+		if len(seats) < 2 {
+			continue
+		}
+
+		current := make([]float64, len(seats))
+		for i, s := range seats {
+			current[i] = cfg.Initial
+			if r, ok := ratings[s.DeckID]; ok && s.DeckID != "" {
+				current[i] = r.Rating
+			}
+		}
+
+		for i, s := range seats {
+			if s.DeckID == "" {
+				continue
+			}
+
+			var delta float64
+			for j, o := range seats {
+				if i == j {
+					continue
+				}
+				expected := 1 / (1 + math.Pow(10, (current[j]-current[i])/eloScale))
+				var score float64
+				switch a, b := g.standing(s), g.standing(o); {
+				case a > b:
+					score = 1
+				case a == b:
+					score = 0.5
+				}
+				delta += score - expected
+			}
+			delta *= cfg.K / float64(len(seats)-1)
+
+			r, ok := ratings[s.DeckID]
+			if !ok {
+				r = &DeckRating{DeckID: s.DeckID, UserID: g.UserID, Rating: cfg.Initial}
+				ratings[s.DeckID] = r
+			}
+			after := math.Round((current[i]+delta)*100) / 100
+
+			changes = append(changes, RatingChange{
+				DeckID:       s.DeckID,
+				GameID:       g.ID,
+				UserID:       g.UserID,
+				PlayedAt:     g.PlayedAt,
+				RatingBefore: current[i],
+				RatingAfter:  after,
+			})
+			r.Rating = after
+			r.Games++
+			r.UpdatedAt = g.PlayedAt
+		}
+	}
+
+	leaderboard := make([]DeckRating, 0, len(ratings))
+	for _, r := range ratings {
+		leaderboard = append(leaderboard, *r)
+	}
+	slices.SortFunc(leaderboard, func(a, b DeckRating) int {
+		if c := cmp.Compare(b.Rating, a.Rating); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.DeckID, b.DeckID)
+	})
+
+	return leaderboard, changes
+}
+
+// RecomputeRatings rates every game the user has played from the start and
+// replaces their stored ratings.
+func (s *Service) RecomputeRatings(ctx context.Context, user users.User) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := recomputeRatings(ctx, tx, s.ratings, user.ID, time.Time{}); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// recomputeRatings redoes the user's ratings as part of a transaction. It runs
+// whenever the user's games change so games logged late or edited land in
+// the right place. Only games played at or after since are rated again. The
+// ratings from before since are taken from the stored history.
+func recomputeRatings(ctx context.Context, tx *sqlx.Tx, cfg RatingConfig, userID string, since time.Time) error {
+
+	// Rating changes to the same user's games have to take turns. Rows can't
+	// be locked for this since a user's first game has nothing to lock yet.
+	const lockQ = `SELECT pg_advisory_xact_lock(hashtext('ratings:' || $1))`
+
+	if _, err := tx.ExecContext(ctx, lockQ, userID); err != nil {
+		return fmt.Errorf("could not lock ratings: %w", err)
+	}
+
+	// Each deck's last rating before since. Ties on played_at are broken the
+	// same way games are ordered below.
+	const startQ = `
+	SELECT
+		h.deck_id,
+		h.user_id,
+		(ARRAY_AGG(h.rating_after ORDER BY h.played_at DESC, g.created_at DESC))[1] AS rating,
+		COUNT(*) AS games,
+		MAX(h.played_at) AS updated_at
+	FROM rating_history h
+	JOIN games g ON g.id = h.game_id
+	WHERE h.user_id = $1
+		AND h.played_at < $2
+	GROUP BY h.deck_id, h.user_id`
+
+	start := []DeckRating{}
+	if err := tx.SelectContext(ctx, &start, startQ, userID, since); err != nil {
+		return err
+	}
+
+	const q = `
+	SELECT
+		id,
+		user_id,
+		deck_id,
+		played_at,
+		format,
+		result,
+		end_reason,
+		turns,
+		notes,
+		created_at,
+		updated_at
+	FROM games
+	WHERE user_id = $1
+		AND played_at >= $2
+	ORDER BY played_at, created_at`
+
+	games := []Game{}
+	if err := tx.SelectContext(ctx, &games, q, userID, since); err != nil {
+		return err
+	}
+	if err := addGameSeats(ctx, tx, games); err != nil {
+		return fmt.Errorf("could not load seats: %w", err)
+	}
+
+	ratings, changes := RateGamesFrom(cfg, start, games)
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM rating_history WHERE user_id = $1 AND played_at >= $2`, userID, since); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM deck_ratings WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+
+	const ratingQ = `
+	INSERT INTO deck_ratings (
+		deck_id,
+		user_id,
+		rating,
+		games,
+		updated_at
+	) VALUES (
+		:deck_id,
+		:user_id,
+		:rating,
+		:games,
+		:updated_at
+	)`
+
+	for _, r := range ratings {
+		if _, err := tx.NamedExecContext(ctx, ratingQ, r); err != nil {
+			return err
+		}
+	}
+
+	const historyQ = `
+	INSERT INTO rating_history (
+		deck_id,
+		game_id,
+		user_id,
+		played_at,
+		rating_before,
+		rating_after
+	) VALUES (
+		:deck_id,
+		:game_id,
+		:user_id,
+		:played_at,
+		:rating_before,
+		:rating_after
+	)`
+
+	for _, c := range changes {
+		if _, err := tx.NamedExecContext(ctx, historyQ, c); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RecomputeAllRatings recomputes the ratings of every user with games. It is
+// used when the rating parameters change.
+func (s *Service) RecomputeAllRatings(ctx context.Context) (int, error) {
+	ids := []string{}
+	if err := s.db.SelectContext(ctx, &ids, `SELECT DISTINCT user_id FROM games`); err != nil {
+		return 0, err
+	}
+
+	for _, id := range ids {
+		if err := s.RecomputeRatings(ctx, users.User{ID: id}); err != nil {
+			return 0, fmt.Errorf("could not recompute ratings for user %s: %w", id, err)
+		}
+	}
+
+	return len(ids), nil
+}
+
+// GetLeaderboard lists the ratings of the user's decks, best first.
+func (s *Service) GetLeaderboard(ctx context.Context, user users.User) ([]DeckRating, error) {
+
+	const q = `
+	SELECT
+		r.deck_id,
+		r.user_id,
+		d.name AS deck_name,
+		r.rating,
+		r.games,
+		r.updated_at
+	FROM deck_ratings r
+	JOIN decks d ON d.id = r.deck_id
+	WHERE r.user_id = $1
+		AND d.deleted_at IS NULL
+	ORDER BY r.rating DESC`
+
+	ratings := []DeckRating{}
+	err := s.db.SelectContext(ctx, &ratings, q, user.ID)
+	return ratings, err
+}
+
+// GetRatingHistory lists how every game changed the rating of one of the
+// user's decks, oldest first.
+func (s *Service) GetRatingHistory(ctx context.Context, user users.User, deckID string) ([]RatingChange, error) {
+	if _, err := s.deckFormat(ctx, user, deckID); err != nil {
+		if errors.Is(err, ErrDeckNotFound) {
+			return nil, ErrDeckNotFound
+		}
+		return nil, err
+	}
+
+	const q = `
+	SELECT
+		deck_id,
+		game_id,
+		user_id,
+		played_at,
+		rating_before,
+		rating_after
+	FROM rating_history
+	WHERE deck_id = $1
+		AND user_id = $2
+	ORDER BY played_at`
+
+	changes := []RatingChange{}
+	err := s.db.SelectContext(ctx, &changes, q, deckID, user.ID)
+	return changes, err
+}
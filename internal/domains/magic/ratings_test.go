@@ -0,0 +1,81 @@
+package magic
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRateGames(t *testing.T) {
+	day := time.Date(2024, 11, 1, 19, 0, 0, 0, time.UTC)
+
+	games := []Game{
+		// A quick 1v1 win against someone unknown.
+		{
+			ID:       "g1",
+			PlayedAt: day,
+			Result:   ResultWin,
+			Seats:    []Seat{{You: true, DeckID: "cats", Won: true}},
+		},
+
+		// A pod where the user's cats deck is knocked out second and a friend
+		// borrowing the vials deck wins.
+		{
+			ID:       "g2",
+			PlayedAt: day.Add(time.Hour),
+			Result:   ResultLoss,
+			Seats: []Seat{
+				{Position: 1, You: true, DeckID: "cats", EliminatedOrder: 2},
+				{Position: 2, Player: "Sam", DeckID: "vials", Won: true},
+				{Position: 3, Player: "Alex", EliminatedOrder: 1},
+				{Position: 4, Player: "Kim"},
+			},
+		},
+
+		// A draw between the user's two decks.
+		{
+			ID:       "g3",
+			PlayedAt: day.Add(2 * time.Hour),
+			Result:   ResultDraw,
+			Seats: []Seat{
+				{You: true, DeckID: "cats"},
+				{Player: "Sam", DeckID: "vials"},
+			},
+		},
+	}
+
+	ratings, changes := RateGames(DefaultRatingConfig, games)
+
+	wantChanges := []RatingChange{
+		{DeckID: "cats", GameID: "g1", PlayedAt: day, RatingBefore: 1500, RatingAfter: 1516},
+
+		// Cats (1516) beat Alex but lost to Sam and Kim. Vials beat everyone.
+		{DeckID: "cats", GameID: "g2", PlayedAt: day.Add(time.Hour), RatingBefore: 1516, RatingAfter: 1509.93},
+		{DeckID: "vials", GameID: "g2", PlayedAt: day.Add(time.Hour), RatingBefore: 1500, RatingAfter: 1516.25},
+
+		{DeckID: "cats", GameID: "g3", PlayedAt: day.Add(2 * time.Hour), RatingBefore: 1509.93, RatingAfter: 1510.22},
+		{DeckID: "vials", GameID: "g3", PlayedAt: day.Add(2 * time.Hour), RatingBefore: 1516.25, RatingAfter: 1515.96},
+	}
+	if diff := cmp.Diff(wantChanges, changes); diff != "" {
+		t.Errorf("wrong rating changes:\n%s", diff)
+	}
+
+	wantRatings := []DeckRating{
+		{DeckID: "vials", Rating: 1515.96, Games: 2, UpdatedAt: day.Add(2 * time.Hour)},
+		{DeckID: "cats", Rating: 1510.22, Games: 3, UpdatedAt: day.Add(2 * time.Hour)},
+	}
+	if diff := cmp.Diff(wantRatings, ratings); diff != "" {
+		t.Errorf("wrong ratings:\n%s", diff)
+	}
+
+	// Picking up from the ratings before the last game rates it the same.
+	before, _ := RateGames(DefaultRatingConfig, games[:2])
+	ratings, changes = RateGamesFrom(DefaultRatingConfig, before, games[2:])
+	if diff := cmp.Diff(wantChanges[3:], changes); diff != "" {
+		t.Errorf("wrong resumed rating changes:\n%s", diff)
+	}
+	if diff := cmp.Diff(wantRatings, ratings); diff != "" {
+		t.Errorf("wrong resumed ratings:\n%s", diff)
+	}
+}
@@ -0,0 +1,288 @@
+package magic
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/jcbwlkr/deck-stats/internal/domains/users"
+)
+
+var (
+	ErrInvalidSearch = errors.New("invalid search")
+)
+
+// comparisons are the operators a mana value or color filter can start with.
+// Longer operators come first so "<=" isn't read as "<".
+var comparisons = []string{"<=", ">=", "!=", "<", ">", "="}
+
+// CardSearch is a search over the cards in the user's decks. Text filters
+// match anywhere in the field ignoring case.
+//
+// ManaValue and Color may start with a comparison like Scryfall's. A mana
+// value of ">=5" matches cards costing 5 or more. A Color of "ur" matches
+// cards that are at least blue and red, "=ur" matches cards that are exactly
+// blue and red and "<=ur" matches cards with no colors besides blue and red.
+// "c" or "colorless" matches colorless cards.
+type CardSearch struct {
+	Name      string
+	Text      string
+	Type      string
+	ManaValue string
+	Color     string
+	Rarity    string
+}
+
+// CardMatch is a card found by a search along with every deck it is in.
+type CardMatch struct {
+	Name       string           `json:"name"`
+	ManaCost   string           `json:"mana_cost"`
+	CMC        float64          `json:"cmc"`
+	TypeLine   string           `json:"type_line"`
+	OracleText string           `json:"oracle_text"`
+	Colors     ColorIdentity    `json:"colors"`
+	Rarity     string           `json:"rarity"`
+	Decks      []CardAppearance `json:"decks"`
+}
+
+// CardAppearance is a board of a deck that a card is on.
+type CardAppearance struct {
+	DeckID   string `json:"deck_id"`
+	DeckName string `json:"deck_name"`
+	Board    string `json:"board"`
+	Quantity int    `json:"quantity"`
+}
+
+// SearchRecord is a card in one of the user's decks along with the deck's
+// name.
+type SearchRecord struct {
+	DeckCard
+	DeckName string `db:"deck_name"`
+}
+
+// cardFilter reports if a card matches one part of a search.
+type cardFilter func(c DeckCard) bool
+
+// cardQuery is a search ready to run. Everything but the color is matched by
+// the database. The text filters are ILIKE patterns and are blank when not
+// used as is ManaOp.
+type cardQuery struct {
+	Name      string
+	Text      string
+	Type      string
+	Rarity    string
+	ManaOp    string
+	ManaValue float64
+
+	// color is nil if the search has no color filter. Colors are sets so
+	// they are compared in Go.
+	color cardFilter
+}
+
+// query checks the search and turns it into a cardQuery.
+func (cs CardSearch) query() (cardQuery, error) {
+	q := cardQuery{
+		Name:   likePattern(cs.Name),
+		Text:   likePattern(cs.Text),
+		Type:   likePattern(cs.Type),
+		Rarity: strings.ToLower(strings.TrimSpace(cs.Rarity)),
+	}
+
+	if cs.ManaValue != "" {
+		op, value := comparison(cs.ManaValue)
+		mv, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return cardQuery{}, fmt.Errorf("%w: mana value %q must be a number", ErrInvalidSearch, cs.ManaValue)
+		}
+		q.ManaOp = cmp.Or(op, "=")
+		q.ManaValue = mv
+	}
+
+	if cs.Color != "" {
+		f, err := colorFilter(cs.Color)
+		if err != nil {
+			return cardQuery{}, err
+		}
+		q.color = f
+	}
+
+	if q.Name == "" && q.Text == "" && q.Type == "" && q.Rarity == "" && q.ManaOp == "" && q.color == nil {
+		return cardQuery{}, fmt.Errorf("%w: at least one filter is required", ErrInvalidSearch)
+	}
+	return q, nil
+}
+
+// likePattern is an ILIKE pattern matching the value anywhere in a field. A
+// blank value has a blank pattern.
+func likePattern(value string) string {
+	if value = strings.TrimSpace(value); value == "" {
+		return ""
+	}
+	escape := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return "%" + escape.Replace(value) + "%"
+}
+
+// colorFilter matches cards by their colors. It takes a set of colors that may
+// start with a comparison.
+func colorFilter(value string) (cardFilter, error) {
+	op, set := comparison(value)
+	colors, ok := ParseColorIdentity(set)
+	if strings.EqualFold(set, "c") {
+		colors, ok = Colorless, true
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown color %q", ErrInvalidSearch, value)
+	}
+	if len(colors) == 0 && op == "" {
+		op = "=" // Every card has at least no colors
+	}
+
+	return func(c DeckCard) bool {
+		within := !slices.ContainsFunc(c.Colors.Normalize(), func(color Color) bool {
+			return !slices.Contains(colors, color)
+		})
+		has := !slices.ContainsFunc(colors, func(color Color) bool {
+			return !slices.Contains(c.Colors.Normalize(), color)
+		})
+		switch op {
+		case "=":
+			return within && has
+		case "!=":
+			return !(within && has)
+		case "<=":
+			return within
+		case "<":
+			return within && !has
+		case ">":
+			return has && !within
+		}
+		return has
+	}, nil
+}
+
+// comparison splits the operator from the start of a filter value. A value
+// without one has a blank operator.
+func comparison(s string) (string, string) {
+	s = strings.TrimSpace(s)
+	for _, op := range comparisons {
+		if rest, ok := strings.CutPrefix(s, op); ok {
+			return op, strings.TrimSpace(rest)
+		}
+	}
+	return "", s
+}
+
+// searchCards groups every copy of the cards found by a query together. The
+// records must already match everything in the query but the color which is
+// checked here. Matches are sorted by name and a card's decks by deck name
+// then board.
+func searchCards(records []SearchRecord, q cardQuery) []CardMatch {
+	var matches []*CardMatch
+	byName := map[string]*CardMatch{}
+
+	for _, r := range records {
+		if q.color != nil && !q.color(r.DeckCard) {
+			continue
+		}
+
+		m, ok := byName[r.Name]
+		if !ok {
+			m = &CardMatch{
+				Name:       r.Name,
+				ManaCost:   r.ManaCost,
+				CMC:        r.CMC,
+				TypeLine:   r.TypeLine,
+				OracleText: r.OracleText,
+				Colors:     r.Colors,
+				Rarity:     r.Rarity,
+			}
+			byName[r.Name] = m
+			matches = append(matches, m)
+		}
+
+		i := slices.IndexFunc(m.Decks, func(a CardAppearance) bool {
+			return a.DeckID == r.DeckID && a.Board == r.Board
+		})
+		if i >= 0 {
+			m.Decks[i].Quantity += r.Quantity
+			continue
+		}
+		m.Decks = append(m.Decks, CardAppearance{
+			DeckID:   r.DeckID,
+			DeckName: r.DeckName,
+			Board:    r.Board,
+			Quantity: r.Quantity,
+		})
+	}
+
+	results := make([]CardMatch, 0, len(matches))
+	for _, m := range matches {
+		slices.SortFunc(m.Decks, func(a, b CardAppearance) int {
+			if c := strings.Compare(a.DeckName, b.DeckName); c != 0 {
+				return c
+			}
+			if c := strings.Compare(a.DeckID, b.DeckID); c != 0 {
+				return c
+			}
+			return strings.Compare(a.Board, b.Board)
+		})
+		results = append(results, *m)
+	}
+	slices.SortFunc(results, func(a, b CardMatch) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return results
+}
+
+// SearchDeckCards searches the cards in the user's decks. Decks removed from
+// their service are left out.
+func (s *Service) SearchDeckCards(ctx context.Context, user users.User, cs CardSearch) ([]CardMatch, error) {
+	q, err := cs.query()
+	if err != nil {
+		return nil, err
+	}
+
+	const search = `
+	SELECT
+		c.deck_id,
+		d.name AS deck_name,
+		c.board,
+		c.quantity,
+		c.name,
+		c.rarity,
+		c.mana_cost,
+		c.cmc,
+		c.type_line,
+		c.oracle_text,
+		c.colors
+	FROM deck_cards c
+	JOIN decks d ON d.id = c.deck_id
+	WHERE d.user_id = $1
+		AND d.deleted_at IS NULL
+		AND ($2 = '' OR c.name ILIKE $2)
+		AND ($3 = '' OR c.oracle_text ILIKE $3)
+		AND ($4 = '' OR c.type_line ILIKE $4)
+		AND ($5 = '' OR LOWER(c.rarity) = $5)
+		AND CASE $6
+			WHEN '' THEN TRUE
+			WHEN '<=' THEN c.cmc <= $7
+			WHEN '>=' THEN c.cmc >= $7
+			WHEN '!=' THEN c.cmc <> $7
+			WHEN '<' THEN c.cmc < $7
+			WHEN '>' THEN c.cmc > $7
+			ELSE c.cmc = $7
+		END`
+
+	records := []SearchRecord{}
+	err = s.db.SelectContext(ctx, &records, search, user.ID, q.Name, q.Text, q.Type, q.Rarity, q.ManaOp, q.ManaValue)
+	if err != nil {
+		return nil, err
+	}
+
+	return searchCards(records, q), nil
+}
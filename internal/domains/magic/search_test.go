@@ -0,0 +1,126 @@
+package magic
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestSearchCards(t *testing.T) {
+	rhystic := DeckCard{Name: "Rhystic Study", ManaCost: "{2}{U}", CMC: 3, TypeLine: "Enchantment", OracleText: "Whenever an opponent casts a spell, you may draw a card unless that player pays {1}.", Colors: MonoBlue, Rarity: "common"}
+	ring := DeckCard{Name: "Sol Ring", ManaCost: "{1}", CMC: 1, TypeLine: "Artifact", OracleText: "{T}: Add {C}{C}.", Colors: Colorless, Rarity: "uncommon"}
+	arahbo := DeckCard{Name: "Arahbo, Roar of the World", ManaCost: "{3}{G}{W}", CMC: 5, TypeLine: "Legendary Creature — Cat Avatar", OracleText: "Eminence — At the beginning of combat on your turn, if Arahbo is in the command zone or on the battlefield, another target Cat you control gets +3/+3 until end of turn.", Colors: Selesnya, Rarity: "mythic"}
+	teferi := DeckCard{Name: "Teferi's Protection", ManaCost: "{2}{W}", CMC: 3, TypeLine: "Instant", OracleText: "Until your next turn, your life total can't change and you gain protection from everything.", Colors: MonoWhite, Rarity: "rare"}
+
+	record := func(c DeckCard, deckID, deckName, board string, quantity int) SearchRecord {
+		c.DeckID = deckID
+		c.Board = board
+		c.Quantity = quantity
+		return SearchRecord{DeckCard: c, DeckName: deckName}
+	}
+
+	records := []SearchRecord{
+		record(rhystic, "cats", "Cats!", BoardMainboard, 1),
+		record(ring, "cats", "Cats!", BoardMainboard, 1),
+		record(arahbo, "cats", "Cats!", BoardCommanders, 1),
+		record(teferi, "cats", "Cats!", BoardMaybeboard, 1),
+		record(rhystic, "blanka", "Blanka never loses!", BoardMainboard, 1),
+		record(ring, "blanka", "Blanka never loses!", BoardMainboard, 1),
+		record(ring, "blanka", "Blanka never loses!", BoardMainboard, 1), // Another printing
+		record(teferi, "winota", "Winota Ryder", BoardSideboard, 1),
+	}
+
+	names := func(matches []CardMatch) []string {
+		var names []string
+		for _, m := range matches {
+			names = append(names, m.Name)
+		}
+		return names
+	}
+
+	tests := []struct {
+		name   string
+		search CardSearch
+		want   []string
+	}{
+		{"color", CardSearch{Color: "w"}, []string{"Arahbo, Roar of the World", "Teferi's Protection"}},
+		{"exact color", CardSearch{Color: "=w"}, []string{"Teferi's Protection"}},
+		{"within colors", CardSearch{Color: "<=wu"}, []string{"Rhystic Study", "Sol Ring", "Teferi's Protection"}},
+		{"color by name", CardSearch{Color: "selesnya"}, []string{"Arahbo, Roar of the World"}},
+		{"colorless", CardSearch{Color: "c"}, []string{"Sol Ring"}},
+		{"no color", CardSearch{Name: "Sol Ring"}, []string{"Arahbo, Roar of the World", "Rhystic Study", "Sol Ring", "Teferi's Protection"}},
+		{"no matches", CardSearch{Color: "b"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := tt.search.query()
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := searchCards(records, q)
+			if diff := cmp.Diff(tt.want, names(got)); diff != "" {
+				t.Errorf("wrong cards:\n%s", diff)
+			}
+		})
+	}
+
+	q, err := CardSearch{Color: "c"}.query()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := searchCards(records, q)
+	want := []CardAppearance{
+		{DeckID: "blanka", DeckName: "Blanka never loses!", Board: BoardMainboard, Quantity: 2},
+		{DeckID: "cats", DeckName: "Cats!", Board: BoardMainboard, Quantity: 1},
+	}
+	if diff := cmp.Diff(want, got[0].Decks); diff != "" {
+		t.Errorf("wrong decks for Sol Ring:\n%s", diff)
+	}
+}
+
+func TestCardSearchQuery(t *testing.T) {
+	tests := []struct {
+		name   string
+		search CardSearch
+		want   cardQuery
+	}{
+		{"name", CardSearch{Name: " rhystic "}, cardQuery{Name: "%rhystic%"}},
+		{"text", CardSearch{Text: "draw a card"}, cardQuery{Text: "%draw a card%"}},
+		{"type", CardSearch{Type: "creature"}, cardQuery{Type: "%creature%"}},
+		{"wildcards", CardSearch{Name: `100%_\`}, cardQuery{Name: `%100\%\_\\%`}},
+		{"rarity", CardSearch{Rarity: "Mythic"}, cardQuery{Rarity: "mythic"}},
+		{"mana value", CardSearch{ManaValue: "3"}, cardQuery{ManaOp: "=", ManaValue: 3}},
+		{"mana value at least", CardSearch{ManaValue: ">= 5"}, cardQuery{ManaOp: ">=", ManaValue: 5}},
+		{"mana value less than", CardSearch{ManaValue: "<3"}, cardQuery{ManaOp: "<", ManaValue: 3}},
+		{"combined", CardSearch{Type: "instant", ManaValue: "3"}, cardQuery{Type: "%instant%", ManaOp: "=", ManaValue: 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.search.query()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, got, cmpopts.IgnoreUnexported(cardQuery{})); diff != "" {
+				t.Errorf("wrong query:\n%s", diff)
+			}
+		})
+	}
+
+	q, err := CardSearch{Color: "w"}.query()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.color == nil {
+		t.Error("color search should filter colors")
+	}
+
+	for _, cs := range []CardSearch{{}, {ManaValue: "three"}, {Color: "purple"}} {
+		if _, err := cs.query(); !errors.Is(err, ErrInvalidSearch) {
+			t.Errorf("search %+v should fail with ErrInvalidSearch but got %v", cs, err)
+		}
+	}
+}
@@ -2,6 +2,8 @@ package magic
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
@@ -11,121 +13,111 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 
 	"github.com/jcbwlkr/deck-stats/internal/domains/users"
-	"github.com/jcbwlkr/deck-stats/internal/services"
 )
 
-type Moxfield interface {
+var (
+	ErrUnknownService = errors.New("unknown service")
+)
+
+// DeckSource is a site that decks can be synced from.
+type DeckSource interface {
 	ListDecks(ctx context.Context, username string) ([]Deck, error)
 	AddDeckDetails(ctx context.Context, d *Deck) error
 }
 
+// Sources maps service names from the services package to the DeckSource that
+// syncs decks from that service.
+type Sources map[string]DeckSource
+
 type Service struct {
-	db *sqlx.DB
-	us *users.Service
-	mc Moxfield
-	wg sync.WaitGroup
+	db      *sqlx.DB
+	us      *users.Service
+	sources Sources
+	cards   CardLookup
+	ratings RatingConfig
+	wg      sync.WaitGroup
+
+	// ctx is the parent of every background refresh. It is canceled when the
+	// service shuts down.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// wake is signaled when a job is queued so workers don't wait to poll.
+	wake chan struct{}
+
+	events *broker
 }
 
-func NewService(db *sqlx.DB, us *users.Service, mc Moxfield) *Service {
-	return &Service{db: db, us: us, mc: mc, wg: sync.WaitGroup{}}
+func NewService(db *sqlx.DB, us *users.Service, sources Sources, cards CardLookup, ratings RatingConfig) *Service {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Service{
+		db:      db,
+		us:      us,
+		sources: sources,
+		cards:   cards,
+		ratings: ratings,
+		wg:      sync.WaitGroup{},
+		ctx:     ctx,
+		cancel:  cancel,
+		wake:    make(chan struct{}),
+		events:  newBroker(),
+	}
 }
 
-func (s *Service) Wait() {
+// Shutdown stops the refresh workers. Running refreshes are canceled and put
+// back in the queue to be resumed on the next start.
+func (s *Service) Shutdown() {
+	s.cancel()
 	s.wg.Wait()
 }
 
-func (s *Service) RefreshDecks(user users.User, account users.Account) {
-
-	// Make a new context for these goroutines.
-	ctx := context.Background()
-
-	log := slog.With("user", user.ID, "service", account.Service)
-
-	// Channel for these two goroutines to talk to each other.
-	result := make(chan error)
-
-	// Goroutine that updates decks for this service.
-	s.wg.Add(1)
-	go func(account users.Account) {
-		defer s.wg.Done()
-		defer close(result) // In case of panic, let other goroutine terminate
-
-		switch account.Service {
-		case services.Moxfield:
-			result <- s.refreshMoxfield(ctx, log, user, account)
-		default:
-			result <- errors.New("unknown service for refresh")
-		}
-	}(account)
-
-	// Goroutine that periodically marks the account as either still refreshing
-	// or completed/failed.
-	s.wg.Add(1)
-	go func(account users.Account) {
-		defer s.wg.Done()
-		now := time.Now()
-		account.RefreshStartedAt = &now
-		account.RefreshActiveAt = &now
-		account.RefreshCompletedAt = nil
-		account.RefreshStatus = "pending"
-		if err := s.us.UpdateAccount(ctx, account); err != nil {
-			log.ErrorContext(ctx, "account status goroutine died", "error", err)
-			return
-		}
-
-		tick := time.NewTicker(10 * time.Second)
-		defer tick.Stop()
-
-		for {
-			select {
-			case now := <-tick.C:
-				log.InfoContext(ctx, "service still refreshing")
-				account.RefreshActiveAt = &now
-				if err := s.us.UpdateAccount(ctx, account); err != nil {
-					log.ErrorContext(ctx, "account status goroutine died", "error", err)
-					return
-				}
+// refreshSource syncs the account's decks from its service. New decks are
+// added, changed or restored decks are updated and decks that are no longer
+// on the service are deleted.
+func (s *Service) refreshSource(ctx context.Context, logger *slog.Logger, src DeckSource, user users.User, account users.Account) (RefreshTotals, error) {
+	start := time.Now()
 
-			case err := <-result:
-				if err != nil {
-					log.ErrorContext(ctx, "failed to refresh", "error", err)
-					account.RefreshStatus = "failed: " + err.Error()
-				} else {
-					log.InfoContext(ctx, "refresh complete")
-					account.RefreshStatus = "completed"
-				}
-				now := time.Now()
-				account.RefreshCompletedAt = &now
-				if err := s.us.UpdateAccount(ctx, account); err != nil {
-					log.ErrorContext(ctx, "account status goroutine died", "error", err)
-				}
-				return
-			}
-		}
-	}(account)
-}
+	var totals RefreshTotals
+	progress := func(deck Deck, state string) {
+		totals.add(state)
+		s.events.publish(RefreshEvent{
+			Type:      EventDeck,
+			AccountID: account.ID,
+			Deck:      &DeckProgress{ID: deck.ID, Name: deck.Name, State: state},
+		})
+	}
 
-func (s *Service) refreshMoxfield(ctx context.Context, logger *slog.Logger, user users.User, account users.Account) error {
-	start := time.Now()
+	existingDecks, err := s.GetDecksForAccount(ctx, user, account)
+	if err != nil {
+		return totals, fmt.Errorf("could not list existing decks: %w", err)
+	}
 
-	existingDecks, err := s.GetDecksForUserAndService(ctx, user, account.Service)
+	cardless, err := s.cardlessDecks(ctx, existingDecks)
 	if err != nil {
-		return fmt.Errorf("could not list existing decks: %w", err)
+		return totals, fmt.Errorf("could not check existing decks for cards: %w", err)
 	}
 
-	moxfieldDecks, err := s.mc.ListDecks(ctx, account.Username)
+	sourceDecks, err := src.ListDecks(ctx, account.Username)
 	if err != nil {
-		return fmt.Errorf("could not list moxfield decks: %w", err)
+		return totals, fmt.Errorf("could not list %s decks: %w", account.Service, err)
 	}
 
-	for _, moxDeck := range moxfieldDecks {
-		log := logger.With("name", moxDeck.Name)
+	totals.Found = len(sourceDecks)
+	s.events.publish(RefreshEvent{
+		Type:      EventDiscovered,
+		AccountID: account.ID,
+		Decks:     len(sourceDecks),
+	})
+
+	for _, srcDeck := range sourceDecks {
+		log := logger.With("name", srcDeck.Name)
 
 		// Look for this deck in our db results
 		i := slices.IndexFunc(existingDecks, func(d Deck) bool {
-			return d.ServiceID == moxDeck.ServiceID
+			return d.ServiceID == srcDeck.ServiceID
 		})
 
 		var deck *Deck
@@ -136,53 +128,132 @@ func (s *Service) refreshMoxfield(ctx context.Context, logger *slog.Logger, user
 		switch {
 		case deck == nil:
 			log.Info("new deck found")
-			moxDeck.UserID = user.ID
-			moxDeck.RefreshedAt = time.Now()
-			if err := s.mc.AddDeckDetails(ctx, &moxDeck); err != nil {
-				return err
+			srcDeck.UserID = user.ID
+			srcDeck.AccountID = account.ID
+			srcDeck.RefreshedAt = time.Now()
+			srcDeck.PricesCheckedAt = &srcDeck.RefreshedAt
+			srcDeck.VersionsCheckedAt = &srcDeck.RefreshedAt
+			if err := src.AddDeckDetails(ctx, &srcDeck); err != nil {
+				return totals, err
 			}
-			if err := s.InsertDeck(ctx, moxDeck); err != nil {
-				return err
+			if err := s.InsertDeck(ctx, srcDeck); err != nil {
+				return totals, err
 			}
+			if err := s.CapturePrices(ctx, srcDeck.Cards); err != nil {
+				return totals, fmt.Errorf("could not capture prices: %w", err)
+			}
+			progress(srcDeck, DeckNew)
 
-		case deck.RefreshedAt.Before(moxDeck.UpdatedAt):
-			log.Info("stale deck found")
-			moxDeck.ID = deck.ID
-			moxDeck.UserID = deck.UserID
-			moxDeck.RefreshedAt = time.Now()
-			deck.RefreshedAt = moxDeck.RefreshedAt
-			if err := s.mc.AddDeckDetails(ctx, &moxDeck); err != nil {
-				return err
+		case deck.DeletedAt != nil, deck.RefreshedAt.Before(srcDeck.UpdatedAt), cardless[deck.ID]:
+			state := DeckStale
+			if deck.DeletedAt != nil {
+				state = DeckRestored
+			}
+			log.Info("stale deck found", "state", state)
+			srcDeck.ID = deck.ID
+			srcDeck.UserID = deck.UserID
+			srcDeck.AccountID = account.ID
+			srcDeck.RefreshedAt = time.Now()
+			srcDeck.PricesCheckedAt = &srcDeck.RefreshedAt
+			srcDeck.VersionsCheckedAt = &srcDeck.RefreshedAt
+			deck.AccountID = account.ID
+			deck.RefreshedAt = srcDeck.RefreshedAt
+			if err := src.AddDeckDetails(ctx, &srcDeck); err != nil {
+				return totals, err
+			}
+			if err := s.ReplaceDeck(ctx, srcDeck); err != nil {
+				return totals, err
 			}
-			if err := s.UpdateDeck(ctx, moxDeck); err != nil {
-				return err
+			if err := s.CapturePrices(ctx, srcDeck.Cards); err != nil {
+				return totals, fmt.Errorf("could not capture prices: %w", err)
 			}
+			progress(srcDeck, state)
 
 		default:
 			log.Debug("deck is up to date")
+			deck.AccountID = account.ID
 			deck.RefreshedAt = time.Now()
 			if err := s.UpdateDeck(ctx, *deck); err != nil {
-				return err
+				return totals, err
+			}
+
+			// The cards haven't changed but their prices have. Fetch the
+			// details again now and then to keep the price history going.
+			// Services don't list versions either so they are compared on
+			// their own, shorter schedule.
+			pricesDue := deck.pricesDue(deck.RefreshedAt)
+			versionsDue := deck.versionsDue(deck.RefreshedAt)
+			if pricesDue || versionsDue {
+				if err := src.AddDeckDetails(ctx, &srcDeck); err != nil {
+					return totals, err
+				}
+			}
+
+			if pricesDue {
+				log.Debug("capturing prices")
+				if err := s.CapturePrices(ctx, srcDeck.Cards); err != nil {
+					return totals, fmt.Errorf("could not capture prices: %w", err)
+				}
+				deck.PricesCheckedAt = &deck.RefreshedAt
+				if err := s.markPricesChecked(ctx, deck.ID, deck.RefreshedAt); err != nil {
+					return totals, fmt.Errorf("could not capture prices: %w", err)
+				}
 			}
+
+			if versionsDue {
+				log.Debug("checking version")
+				deck.VersionsCheckedAt = &deck.RefreshedAt
+				srcDeck.ID = deck.ID
+				srcDeck.UserID = deck.UserID
+				srcDeck.AccountID = account.ID
+				srcDeck.RefreshedAt = deck.RefreshedAt
+				srcDeck.PricesCheckedAt = deck.PricesCheckedAt
+				srcDeck.VersionsCheckedAt = deck.VersionsCheckedAt
+				if err := s.checkVersion(ctx, log, deck, srcDeck); err != nil {
+					return totals, fmt.Errorf("could not check version: %w", err)
+				}
+				if err := s.markVersionsChecked(ctx, deck.ID, deck.RefreshedAt); err != nil {
+					return totals, fmt.Errorf("could not check version: %w", err)
+				}
+			}
+			progress(*deck, DeckUpToDate)
 		}
 	}
 
+	// Unclaimed decks this account didn't find may be another account's so
+	// they are left alone.
 	for _, eDeck := range existingDecks {
-		if eDeck.RefreshedAt.Before(start) {
-			logger.Info("deleting deck that wasn't on moxfield", "id", eDeck.ID, "name", eDeck.Name)
-			// TODO(jlw) this
+		if eDeck.AccountID == account.ID && eDeck.DeletedAt == nil && eDeck.RefreshedAt.Before(start) {
+			logger.Info("deleting deck that is no longer on the service", "id", eDeck.ID, "name", eDeck.Name)
+			if err := s.DeleteDeck(ctx, eDeck.ID); err != nil {
+				return totals, err
+			}
+			progress(eDeck, DeckDeleted)
 		}
 	}
 
-	return nil
+	return totals, nil
+}
+
+// DeckFilter narrows down the decks listed for a user.
+type DeckFilter struct {
+	IncludeDeleted bool // Include decks removed from their service
+	Illegal        bool // Only decks with cards not legal in their format
 }
 
-func (s *Service) GetDecksForUser(ctx context.Context, user users.User) ([]Deck, error) {
+// GetDecksForUser lists the user's decks. Decks that have been removed from
+// their service are only included if the filter asks for them.
+func (s *Service) GetDecksForUser(ctx context.Context, user users.User, filter DeckFilter) ([]Deck, error) {
 
+	// The illegal filter only checks card legality the way ValidateDeck does.
+	// The Commander construction rules ValidateDeck also applies are not
+	// checked.
+	// $3 maps deck formats to their legality keys when the two differ.
 	const q = `
 	SELECT
 		id,
 		user_id,
+		COALESCE(account_id, '') AS account_id,
 		service,
 		service_id,
 		name,
@@ -191,22 +262,67 @@ func (s *Service) GetDecksForUser(ctx context.Context, user users.User) ([]Deck,
 		color_identity,
 		leaders,
 		archetypes,
+		version,
 		updated_at,
-		refreshed_at
-	FROM decks
-	WHERE user_id = $1`
+		refreshed_at,
+		deleted_at,
+		prices_checked_at,
+		versions_checked_at
+	FROM decks d
+	WHERE user_id = $1
+		AND ($2 OR deleted_at IS NULL)
+		AND (NOT $4 OR EXISTS (
+			SELECT 1
+			FROM deck_cards c
+			LEFT JOIN card_legalities cl ON cl.scryfall_id = c.scryfall_id
+			CROSS JOIN LATERAL (
+				SELECT COALESCE(cl.legalities, c.legalities)->>COALESCE($3::jsonb->>d.format, d.format) AS status
+			) l
+			WHERE c.deck_id = d.id
+				AND c.board <> 'maybeboard'
+			GROUP BY c.name, l.status
+			HAVING l.status IN ('not_legal', 'banned')
+				OR (l.status = 'restricted' AND SUM(c.quantity) > 1)
+		))`
+
+	formats, err := json.Marshal(legalityFormats)
+	if err != nil {
+		return nil, err
+	}
 
 	decks := []Deck{}
-	err := s.db.SelectContext(ctx, &decks, q, user.ID)
+	err = s.db.SelectContext(ctx, &decks, q, user.ID, filter.IncludeDeleted, string(formats), filter.Illegal)
 	return decks, err
 }
 
-func (s *Service) GetDecksForUserAndService(ctx context.Context, user users.User, service string) ([]Deck, error) {
+// GetColorReport tallies the color identities of the user's decks. If format
+// is not blank only decks of that format are included.
+func (s *Service) GetColorReport(ctx context.Context, user users.User, format string) (ColorReport, error) {
+
+	const q = `
+	SELECT color_identity
+	FROM decks
+	WHERE user_id = $1
+		AND deleted_at IS NULL
+		AND ($2 = '' OR format = $2)`
+
+	identities := []ColorIdentity{}
+	if err := s.db.SelectContext(ctx, &identities, q, user.ID, format); err != nil {
+		return ColorReport{}, err
+	}
+
+	return NewColorReport(format, identities), nil
+}
+
+// GetDeck loads a single deck owned by the user, including its cards. Decks
+// removed from their service are not found.
+func (s *Service) GetDeck(ctx context.Context, user users.User, id string) (Deck, error) {
 
 	const q = `
 	SELECT
 		id,
 		user_id,
+		COALESCE(account_id, '') AS account_id,
 		service,
 		service_id,
 		name,
@@ -215,14 +331,65 @@ func (s *Service) GetDecksForUserAndService(ctx context.Context, user users.User
 		color_identity,
 		leaders,
 		archetypes,
+		version,
 		updated_at,
-		refreshed_at
+		refreshed_at,
+		deleted_at,
+		prices_checked_at,
+		versions_checked_at
+	FROM decks
+	WHERE id = $1
+		AND user_id = $2
+		AND deleted_at IS NULL`
+
+	var deck Deck
+	if err := s.db.GetContext(ctx, &deck, q, id, user.ID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Deck{}, ErrDeckNotFound
+		}
+		return Deck{}, err
+	}
+
+	cards, err := s.GetDeckCards(ctx, deck.ID)
+	if err != nil {
+		return Deck{}, fmt.Errorf("could not load cards: %w", err)
+	}
+	deck.Cards = cards
+
+	return deck, nil
+}
+
+// GetDecksForAccount lists every deck synced from the account including those
+// removed from the service. Decks of the user's on the account's service that
+// no account has claimed yet are included too.
+func (s *Service) GetDecksForAccount(ctx context.Context, user users.User, account users.Account) ([]Deck, error) {
+
+	const q = `
+	SELECT
+		id,
+		user_id,
+		COALESCE(account_id, '') AS account_id,
+		service,
+		service_id,
+		name,
+		format,
+		url,
+		color_identity,
+		leaders,
+		archetypes,
+		version,
+		updated_at,
+		refreshed_at,
+		deleted_at,
+		prices_checked_at,
+		versions_checked_at
 	FROM decks
 	WHERE user_id = $1
-		AND service = $2`
+		AND service = $2
+		AND (account_id = $3 OR account_id IS NULL)`
 
 	decks := []Deck{}
-	err := s.db.SelectContext(ctx, &decks, q, user.ID, service)
+	err := s.db.SelectContext(ctx, &decks, q, user.ID, account.Service, account.ID)
 	return decks, err
 }
 
@@ -232,6 +399,7 @@ func (s *Service) InsertDeck(ctx context.Context, deck Deck) error {
 	INSERT INTO decks (
 		id,
 		user_id,
+		account_id,
 		service,
 		service_id,
 		name,
@@ -240,11 +408,15 @@ func (s *Service) InsertDeck(ctx context.Context, deck Deck) error {
 		color_identity,
 		leaders,
 		archetypes,
+		version,
 		updated_at,
-		refreshed_at
+		refreshed_at,
+		prices_checked_at,
+		versions_checked_at
 	) VALUES (
 		:id,
 		:user_id,
+		NULLIF(:account_id, ''),
 		:service,
 		:service_id,
 		:name,
@@ -253,30 +425,263 @@ func (s *Service) InsertDeck(ctx context.Context, deck Deck) error {
 		:color_identity,
 		:leaders,
 		:archetypes,
+		:version,
 		:updated_at,
-		:refreshed_at
+		:refreshed_at,
+		:prices_checked_at,
+		:versions_checked_at
 	)`
 
-	deck.ID = uuid.New().String()
+	if deck.ID == "" {
+		deck.ID = uuid.New().String()
+	}
 
-	_, err := s.db.NamedExecContext(ctx, q, deck)
-	return err
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.NamedExecContext(ctx, q, deck); err != nil {
+		return err
+	}
+
+	if err := replaceDeckCards(ctx, tx, deck.ID, deck.Cards); err != nil {
+		return err
+	}
+
+	if err := snapshotDeck(ctx, tx, deck); err != nil {
+		return fmt.Errorf("could not snapshot deck: %w", err)
+	}
+
+	return tx.Commit()
 }
 
 func (s *Service) UpdateDeck(ctx context.Context, deck Deck) error {
+	return updateDeck(ctx, s.db, deck)
+}
+
+func updateDeck(ctx context.Context, db sqlx.ExtContext, deck Deck) error {
 	const q = `
 	UPDATE decks SET
+		account_id = NULLIF(:account_id, ''),
 		name = :name,
 		format = :format,
 		url = :url,
 		color_identity = :color_identity,
 		leaders = :leaders,
 		archetypes = :archetypes,
+		version = :version,
 		updated_at = :updated_at,
-		refreshed_at = :refreshed_at
+		refreshed_at = :refreshed_at,
+		deleted_at = :deleted_at,
+		prices_checked_at = :prices_checked_at,
+		versions_checked_at = :versions_checked_at
 	WHERE id = :id
 	`
 
-	_, err := s.db.NamedExecContext(ctx, q, deck)
+	_, err := sqlx.NamedExecContext(ctx, db, q, deck)
 	return err
 }
+
+// CreateAccount links the user to an account on one of the services we can
+// sync decks from.
+func (s *Service) CreateAccount(ctx context.Context, user users.User, na users.NewAccount) (users.Account, error) {
+	if _, ok := s.sources[na.Service]; !ok {
+		return users.Account{}, fmt.Errorf("%w %q", ErrUnknownService, na.Service)
+	}
+	return s.us.CreateAccount(ctx, user.ID, na)
+}
+
+// DeleteAccount removes one of the user's accounts. The decks synced from it
+// are soft deleted rather than removed so the games played with them are kept.
+func (s *Service) DeleteAccount(ctx context.Context, user users.User, account users.Account) error {
+
+	const q = `
+	UPDATE decks SET
+		deleted_at = $3
+	WHERE account_id = $1
+		AND user_id = $2
+		AND deleted_at IS NULL`
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, q, account.ID, user.ID, time.Now()); err != nil {
+		return fmt.Errorf("could not delete decks: %w", err)
+	}
+
+	if err := s.us.DeleteAccount(ctx, tx, account.ID, user.ID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DeleteDeck soft deletes a deck so it no longer shows up in stats. The deck
+// is restored if it is found again on a later refresh.
+func (s *Service) DeleteDeck(ctx context.Context, id string) error {
+	const q = `
+	UPDATE decks SET
+		deleted_at = $2
+	WHERE id = $1`
+
+	_, err := s.db.ExecContext(ctx, q, id, time.Now())
+	return err
+}
+
+// ReplaceDeck updates the deck and replaces its stored card list with
+// deck.Cards together so the two can't get out of sync. A snapshot of the
+// cards is kept if the deck's version or update time is new.
+func (s *Service) ReplaceDeck(ctx context.Context, deck Deck) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := updateDeck(ctx, tx, deck); err != nil {
+		return err
+	}
+
+	if err := replaceDeckCards(ctx, tx, deck.ID, deck.Cards); err != nil {
+		return err
+	}
+
+	if err := snapshotDeck(ctx, tx, deck); err != nil {
+		return fmt.Errorf("could not snapshot deck: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// cardlessDecks finds which of the decks have no stored cards. Decks synced
+// before cards were stored are refreshed in full to fill them in.
+func (s *Service) cardlessDecks(ctx context.Context, decks []Deck) (map[string]bool, error) {
+	ids := make([]string, 0, len(decks))
+	for _, d := range decks {
+		ids = append(ids, d.ID)
+	}
+
+	const q = `
+	SELECT d.id
+	FROM unnest($1::TEXT[]) AS d(id)
+	WHERE NOT EXISTS (SELECT 1 FROM deck_cards c WHERE c.deck_id = d.id)`
+
+	found := []string{}
+	if err := s.db.SelectContext(ctx, &found, q, pq.StringArray(ids)); err != nil {
+		return nil, err
+	}
+
+	cardless := make(map[string]bool, len(found))
+	for _, id := range found {
+		cardless[id] = true
+	}
+	return cardless, nil
+}
+
+func (s *Service) GetDeckCards(ctx context.Context, deckID string) ([]DeckCard, error) {
+
+	// Legalities are kept up to date per card. Those stored with the deck
+	// are only used until the card has been checked.
+	const q = `
+	SELECT
+		c.id,
+		c.deck_id,
+		c.board,
+		c.quantity,
+		c.finish,
+		c.is_proxy,
+		c.scryfall_id,
+		c.name,
+		c.set_code,
+		c.collector_number,
+		c.rarity,
+		c.mana_cost,
+		c.cmc,
+		c.type_line,
+		c.oracle_text,
+		c.colors,
+		c.color_identity,
+		COALESCE(l.legalities, c.legalities) AS legalities
+	FROM deck_cards c
+	LEFT JOIN card_legalities l ON l.scryfall_id = c.scryfall_id
+	WHERE c.deck_id = $1
+	ORDER BY c.board, c.name`
+
+	cards := []DeckCard{}
+	err := s.db.SelectContext(ctx, &cards, q, deckID)
+	return cards, err
+}
+
+func replaceDeckCards(ctx context.Context, tx *sqlx.Tx, deckID string, cards []DeckCard) error {
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM deck_cards WHERE deck_id = $1`, deckID); err != nil {
+		return err
+	}
+
+	const q = `
+	INSERT INTO deck_cards (
+		id,
+		deck_id,
+		board,
+		quantity,
+		finish,
+		is_proxy,
+		scryfall_id,
+		name,
+		set_code,
+		collector_number,
+		rarity,
+		mana_cost,
+		cmc,
+		type_line,
+		oracle_text,
+		colors,
+		color_identity,
+		legalities
+	) VALUES (
+		:id,
+		:deck_id,
+		:board,
+		:quantity,
+		:finish,
+		:is_proxy,
+		:scryfall_id,
+		:name,
+		:set_code,
+		:collector_number,
+		:rarity,
+		:mana_cost,
+		:cmc,
+		:type_line,
+		:oracle_text,
+		:colors,
+		:color_identity,
+		:legalities
+	)`
+
+	for _, card := range cards {
+		card.ID = uuid.New().String()
+		card.DeckID = deckID
+		if _, err := tx.NamedExecContext(ctx, q, card); err != nil {
+			return err
+		}
+	}
+
+	// Cards fresh from a service have current legalities.
+	now := time.Now()
+	for _, card := range cards {
+		if card.ScryfallID == "" || len(card.Legalities) == 0 {
+			continue
+		}
+		if err := storeLegalities(ctx, tx, card.ScryfallID, card.Legalities, now); err != nil {
+			return fmt.Errorf("could not store legalities: %w", err)
+		}
+	}
+
+	return nil
+}
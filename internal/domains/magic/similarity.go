@@ -0,0 +1,193 @@
+package magic
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/jcbwlkr/deck-stats/internal/domains/users"
+)
+
+// DefaultSimilarPairs is how many pairs of decks are listed when no limit is
+// asked for.
+const DefaultSimilarPairs = 10
+
+// DeckRef names a deck in a comparison.
+type DeckRef struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// DeckSimilarity is how alike two decks are. Similarity is the weighted
+// Jaccard index of their non-basic cards from 0 for nothing in common to 1 for
+// the same list. Shared counts the different cards both decks run.
+type DeckSimilarity struct {
+	DeckA      DeckRef `json:"deck_a"`
+	DeckB      DeckRef `json:"deck_b"`
+	Similarity float64 `json:"similarity"`
+	Shared     int     `json:"shared"`
+}
+
+// DeckOverlap is the similarity of two decks along with the cards they share.
+type DeckOverlap struct {
+	DeckSimilarity
+	Cards []SharedCard `json:"cards"`
+}
+
+// SharedCard is a card both decks run and how many copies each runs.
+type SharedCard struct {
+	Name      string `json:"name"`
+	QuantityA int    `json:"quantity_a"`
+	QuantityB int    `json:"quantity_b"`
+}
+
+// deckWeights counts the copies of each non-basic card that is part of the
+// deck as it is played.
+func deckWeights(cards []DeckCard) map[string]int {
+	weights := map[string]int{}
+	for _, c := range cards {
+		if !c.InDeck() || c.isBasic() {
+			continue
+		}
+		weights[c.Name] += c.Quantity
+	}
+	return weights
+}
+
+// CompareDecks works out the weighted Jaccard index of the two decks: the
+// copies both decks run over the copies either deck runs.
+func CompareDecks(a, b Deck) DeckOverlap {
+	wa, wb := deckWeights(a.Cards), deckWeights(b.Cards)
+
+	overlap := DeckOverlap{
+		DeckSimilarity: DeckSimilarity{
+			DeckA: DeckRef{ID: a.ID, Name: a.Name},
+			DeckB: DeckRef{ID: b.ID, Name: b.Name},
+		},
+		Cards: []SharedCard{},
+	}
+
+	var both, either int
+	for name, qa := range wa {
+		qb := wb[name]
+		both += min(qa, qb)
+		either += max(qa, qb)
+		if qb > 0 {
+			overlap.Cards = append(overlap.Cards, SharedCard{Name: name, QuantityA: qa, QuantityB: qb})
+		}
+	}
+	for name, qb := range wb {
+		if _, ok := wa[name]; !ok {
+			either += qb
+		}
+	}
+
+	if either > 0 {
+		overlap.Similarity = round(float64(both) / float64(either))
+	}
+	overlap.Shared = len(overlap.Cards)
+
+	slices.SortFunc(overlap.Cards, func(a, b SharedCard) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return overlap
+}
+
+// SimilarDecks compares every pair of decks and returns up to limit of the
+// most similar pairs. Pairs with nothing in common are left out.
+func SimilarDecks(decks []Deck, limit int) []DeckSimilarity {
+	pairs := []DeckSimilarity{}
+	for i := range decks {
+		for j := i + 1; j < len(decks); j++ {
+			pair := CompareDecks(decks[i], decks[j]).DeckSimilarity
+			if pair.Shared == 0 {
+				continue
+			}
+			pairs = append(pairs, pair)
+		}
+	}
+
+	slices.SortFunc(pairs, func(a, b DeckSimilarity) int {
+		return cmp.Or(
+			cmp.Compare(b.Similarity, a.Similarity),
+			cmp.Compare(b.Shared, a.Shared),
+			strings.Compare(a.DeckA.Name, b.DeckA.Name),
+			strings.Compare(a.DeckB.Name, b.DeckB.Name),
+		)
+	})
+
+	if limit > 0 && len(pairs) > limit {
+		pairs = pairs[:limit]
+	}
+	return pairs
+}
+
+// GetSimilarDecks finds the user's most similar pairs of decks. Decks removed
+// from their service are left out.
+func (s *Service) GetSimilarDecks(ctx context.Context, user users.User, limit int) ([]DeckSimilarity, error) {
+	if limit <= 0 {
+		limit = DefaultSimilarPairs
+	}
+
+	records, err := s.userCards(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	var decks []Deck
+	index := map[string]int{}
+	for _, r := range records {
+		i, ok := index[r.DeckID]
+		if !ok {
+			i = len(decks)
+			index[r.DeckID] = i
+			decks = append(decks, Deck{ID: r.DeckID, Name: r.DeckName})
+		}
+		decks[i].Cards = append(decks[i].Cards, r.DeckCard)
+	}
+
+	return SimilarDecks(decks, limit), nil
+}
+
+// GetDeckOverlap compares two of the user's decks.
+func (s *Service) GetDeckOverlap(ctx context.Context, user users.User, deckID, otherID string) (DeckOverlap, error) {
+	a, err := s.GetDeck(ctx, user, deckID)
+	if err != nil {
+		return DeckOverlap{}, err
+	}
+	b, err := s.GetDeck(ctx, user, otherID)
+	if err != nil {
+		return DeckOverlap{}, err
+	}
+
+	return CompareDecks(a, b), nil
+}
+
+// userCards loads the cards in every deck of the user's that is still on its
+// service.
+func (s *Service) userCards(ctx context.Context, user users.User) ([]SearchRecord, error) {
+
+	const q = `
+	SELECT
+		c.deck_id,
+		d.name AS deck_name,
+		c.board,
+		c.quantity,
+		c.name,
+		c.rarity,
+		c.mana_cost,
+		c.cmc,
+		c.type_line,
+		c.oracle_text,
+		c.colors
+	FROM deck_cards c
+	JOIN decks d ON d.id = c.deck_id
+	WHERE d.user_id = $1
+		AND d.deleted_at IS NULL`
+
+	records := []SearchRecord{}
+	err := s.db.SelectContext(ctx, &records, q, user.ID)
+	return records, err
+}
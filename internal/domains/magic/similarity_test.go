@@ -0,0 +1,88 @@
+package magic
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCompareDecks(t *testing.T) {
+	cats := Deck{
+		ID:   "cats",
+		Name: "Cats!",
+		Cards: []DeckCard{
+			{Board: BoardCommanders, Quantity: 1, Name: "Arahbo, Roar of the World", TypeLine: "Legendary Creature — Cat Avatar"},
+			{Board: BoardMainboard, Quantity: 1, Name: "Sol Ring", TypeLine: "Artifact"},
+			{Board: BoardMainboard, Quantity: 1, Name: "Swords to Plowshares", TypeLine: "Instant"},
+			{Board: BoardMainboard, Quantity: 1, Name: "Rhystic Study", TypeLine: "Enchantment"},
+			{Board: BoardMainboard, Quantity: 30, Name: "Plains", TypeLine: "Basic Land — Plains"},
+			{Board: BoardMaybeboard, Quantity: 1, Name: "Cyclonic Rift", TypeLine: "Instant"},
+		},
+	}
+	rats := Deck{
+		ID:   "rats",
+		Name: "Rats",
+		Cards: []DeckCard{
+			{Board: BoardCommanders, Quantity: 1, Name: "Karumonix, the Rat King", TypeLine: "Legendary Creature — Phyrexian Rat"},
+			{Board: BoardMainboard, Quantity: 1, Name: "Sol Ring", TypeLine: "Artifact"},
+			{Board: BoardMainboard, Quantity: 1, Name: "Rhystic Study", TypeLine: "Enchantment"},
+			{Board: BoardMainboard, Quantity: 20, Name: "Relentless Rats", TypeLine: "Creature — Rat"},
+			{Board: BoardMainboard, Quantity: 30, Name: "Swamp", TypeLine: "Basic Land — Swamp"},
+			{Board: BoardSideboard, Quantity: 1, Name: "Swords to Plowshares", TypeLine: "Instant"},
+			{Board: BoardMaybeboard, Quantity: 1, Name: "Cyclonic Rift", TypeLine: "Instant"},
+		},
+	}
+
+	// Sol Ring and Rhystic Study are shared out of the 25 copies of non-basic
+	// cards either deck plays. Swords is only in the rats sideboard.
+	want := DeckOverlap{
+		DeckSimilarity: DeckSimilarity{
+			DeckA:      DeckRef{ID: "cats", Name: "Cats!"},
+			DeckB:      DeckRef{ID: "rats", Name: "Rats"},
+			Similarity: 0.08,
+			Shared:     2,
+		},
+		Cards: []SharedCard{
+			{Name: "Rhystic Study", QuantityA: 1, QuantityB: 1},
+			{Name: "Sol Ring", QuantityA: 1, QuantityB: 1},
+		},
+	}
+
+	if diff := cmp.Diff(want, CompareDecks(cats, rats)); diff != "" {
+		t.Errorf("wrong overlap:\n%s", diff)
+	}
+
+	if got := CompareDecks(cats, cats).Similarity; got != 1 {
+		t.Errorf("a deck should be identical to itself but got %v", got)
+	}
+}
+
+func TestSimilarDecks(t *testing.T) {
+	deck := func(id string, names ...string) Deck {
+		d := Deck{ID: id, Name: id}
+		for _, name := range names {
+			d.Cards = append(d.Cards, DeckCard{Board: BoardMainboard, Quantity: 1, Name: name})
+		}
+		return d
+	}
+
+	decks := []Deck{
+		deck("a", "Sol Ring", "Arcane Signet", "Command Tower"),
+		deck("b", "Sol Ring", "Arcane Signet", "Command Tower", "Rhystic Study"),
+		deck("c", "Sol Ring", "Lightning Bolt"),
+		deck("d", "Forest"),
+	}
+
+	want := []DeckSimilarity{
+		{DeckA: DeckRef{ID: "a", Name: "a"}, DeckB: DeckRef{ID: "b", Name: "b"}, Similarity: 0.75, Shared: 3},
+		{DeckA: DeckRef{ID: "a", Name: "a"}, DeckB: DeckRef{ID: "c", Name: "c"}, Similarity: 0.25, Shared: 1},
+	}
+
+	if diff := cmp.Diff(want, SimilarDecks(decks, 2)); diff != "" {
+		t.Errorf("wrong pairs:\n%s", diff)
+	}
+
+	if got, want := len(SimilarDecks(decks, 10)), 3; got != want {
+		t.Errorf("should find %d pairs with cards in common but got %d", want, got)
+	}
+}
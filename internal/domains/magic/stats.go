@@ -0,0 +1,141 @@
+package magic
+
+import (
+	"math"
+	"strings"
+)
+
+// Card types we recognize when reading a type line.
+var cardTypes = []string{
+	"Artifact",
+	"Battle",
+	"Creature",
+	"Enchantment",
+	"Instant",
+	"Kindred",
+	"Land",
+	"Planeswalker",
+	"Sorcery",
+}
+
+// DeckStats summarizes the cards a deck plays.
+type DeckStats struct {
+	DeckID     string         `json:"deck_id"`
+	Cards      int            `json:"cards"`
+	Lands      int            `json:"lands"`
+	ManaCurve  map[int]int    `json:"mana_curve"`
+	AverageCMC float64        `json:"average_cmc"`
+	Types      map[string]int `json:"types"`
+	Pips       map[Color]int  `json:"pips"`
+}
+
+// ComputeStats builds stats for the cards in the deck that are actually
+// played. Sideboard and maybeboard cards are ignored. Every number is weighted
+// by card quantity.
+func ComputeStats(deck Deck) DeckStats {
+	stats := DeckStats{
+		DeckID:    deck.ID,
+		ManaCurve: map[int]int{},
+		Types:     map[string]int{},
+		Pips:      map[Color]int{},
+	}
+
+	var totalCMC float64
+	var nonLands int
+
+	for _, card := range deck.Cards {
+		if !card.InDeck() {
+			continue
+		}
+
+		stats.Cards += card.Quantity
+
+		for _, t := range card.Types() {
+			stats.Types[t] += card.Quantity
+		}
+
+		for c, n := range card.Pips() {
+			stats.Pips[c] += n * card.Quantity
+		}
+
+		if card.IsLand() {
+			stats.Lands += card.Quantity
+			continue
+		}
+
+		nonLands += card.Quantity
+		totalCMC += card.CMC * float64(card.Quantity)
+		stats.ManaCurve[int(card.CMC)] += card.Quantity
+	}
+
+	if nonLands > 0 {
+		stats.AverageCMC = math.Round(totalCMC/float64(nonLands)*100) / 100
+	}
+
+	return stats
+}
+
+// InDeck reports whether the card is part of the deck as it is played rather
+// than a sideboard or maybeboard consideration.
+func (c DeckCard) InDeck() bool {
+	switch c.Board {
+	case BoardCommanders, BoardSignatureSpells, BoardMainboard:
+		return true
+	}
+	return false
+}
+
+// IsLand reports whether the front face of the card is a land.
+func (c DeckCard) IsLand() bool {
+	for _, t := range c.Types() {
+		if t == "Land" {
+			return true
+		}
+	}
+	return false
+}
+
+// Types returns the card types found on the front face of the card's type
+// line. A card such as an Artifact Creature returns both types.
+func (c DeckCard) Types() []string {
+	line, _, _ := strings.Cut(c.TypeLine, "//")
+	line, _, _ = strings.Cut(line, "—")
+
+	var types []string
+	for _, word := range strings.Fields(line) {
+		for _, t := range cardTypes {
+			if word == t || (word == "Tribal" && t == "Kindred") {
+				types = append(types, t)
+			}
+		}
+	}
+	return types
+}
+
+// Pips counts the colored mana symbols in the mana cost of the front face of
+// the card. Hybrid symbols count once for each of their colors.
+func (c DeckCard) Pips() map[Color]int {
+	cost, _, _ := strings.Cut(c.ManaCost, "//")
+
+	pips := map[Color]int{}
+	for {
+		start := strings.Index(cost, "{")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(cost[start:], "}")
+		if end < 0 {
+			break
+		}
+		symbol := cost[start+1 : start+end]
+		cost = cost[start+end+1:]
+
+		for _, part := range strings.Split(symbol, "/") {
+			switch color := Color(strings.ToLower(part)); color {
+			case White, Blue, Black, Red, Green:
+				pips[color]++
+			}
+		}
+	}
+	return pips
+}
@@ -0,0 +1,45 @@
+package magic
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestComputeStats(t *testing.T) {
+	deck := Deck{
+		ID: "deck-1",
+		Cards: []DeckCard{
+			{Board: BoardCommanders, Quantity: 1, ManaCost: "{1}{B}{R}", CMC: 3, TypeLine: "Legendary Creature — Human Berserker"},
+			{Board: BoardMainboard, Quantity: 1, ManaCost: "{1}", CMC: 1, TypeLine: "Artifact"},
+			{Board: BoardMainboard, Quantity: 1, ManaCost: "{2}{B/R}{B/R}", CMC: 4, TypeLine: "Artifact Creature — Golem"},
+			{Board: BoardMainboard, Quantity: 1, ManaCost: "{R} // {3}{R}{R}", CMC: 1, TypeLine: "Instant // Sorcery"},
+			{Board: BoardMainboard, Quantity: 10, CMC: 0, TypeLine: "Basic Land — Swamp"},
+			{Board: BoardMainboard, Quantity: 1, CMC: 0, TypeLine: "Creature — Human Wizard // Land"},
+			{Board: BoardSideboard, Quantity: 1, ManaCost: "{W}", CMC: 1, TypeLine: "Instant"},
+			{Board: BoardMaybeboard, Quantity: 1, ManaCost: "{G}", CMC: 1, TypeLine: "Sorcery"},
+		},
+	}
+
+	want := DeckStats{
+		DeckID:     "deck-1",
+		Cards:      15,
+		Lands:      10,
+		ManaCurve:  map[int]int{0: 1, 1: 2, 3: 1, 4: 1},
+		AverageCMC: 1.8,
+		Types: map[string]int{
+			"Artifact": 2,
+			"Creature": 3,
+			"Instant":  1,
+			"Land":     10,
+		},
+		Pips: map[Color]int{
+			Black: 3,
+			Red:   4,
+		},
+	}
+
+	if diff := cmp.Diff(want, ComputeStats(deck)); diff != "" {
+		t.Errorf("wrong stats:\n%s", diff)
+	}
+}
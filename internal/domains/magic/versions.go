@@ -0,0 +1,366 @@
+package magic
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/jcbwlkr/deck-stats/internal/domains/users"
+)
+
+var (
+	ErrVersionNotFound = errors.New("deck version not found")
+)
+
+// versionInterval is how often decks that haven't changed are fetched to
+// compare their versions. The version can change without the update time that
+// is listed with the deck.
+const versionInterval = time.Hour
+
+// DeckVersion is a snapshot of a deck's cards as it was at one version on its
+// service. Cards is only populated when a single version is loaded.
+type DeckVersion struct {
+	ID         string       `db:"id" json:"id"`
+	DeckID     string       `db:"deck_id" json:"deck_id"`
+	Version    int          `db:"version" json:"version"`
+	UpdatedAt  time.Time    `db:"updated_at" json:"updated_at"`
+	CapturedAt time.Time    `db:"captured_at" json:"captured_at"`
+	Size       int          `db:"size" json:"size"` // Cards in the deck, maybeboard excluded
+	Cards      VersionCards `db:"cards" json:"cards,omitempty"`
+}
+
+// VersionCard is a card in a snapshot. Only what is needed to tell versions
+// apart is kept.
+type VersionCard struct {
+	Board      string `json:"board"`
+	Name       string `json:"name"`
+	ScryfallID string `json:"scryfall_id"`
+	Quantity   int    `json:"quantity"`
+}
+
+type VersionCards []VersionCard
+
+// DeckDiff lists how the cards changed between two versions of a deck.
+type DeckDiff struct {
+	DeckID  string       `json:"deck_id"`
+	From    DeckVersion  `json:"from"`
+	To      DeckVersion  `json:"to"`
+	Added   []CardChange `json:"added"`
+	Removed []CardChange `json:"removed"`
+	Changed []CardChange `json:"changed"`
+}
+
+// CardChange is how many copies of a card were on a board before and after.
+type CardChange struct {
+	Board string `json:"board"`
+	Name  string `json:"name"`
+	From  int    `json:"from"`
+	To    int    `json:"to"`
+}
+
+// versionCards reduces the deck's cards to a snapshot. Different printings of
+// the same card are counted together.
+func versionCards(cards []DeckCard) VersionCards {
+	type key struct{ board, name string }
+
+	var snapshot VersionCards
+	index := map[key]int{}
+	for _, c := range cards {
+		k := key{c.Board, c.Name}
+		if i, ok := index[k]; ok {
+			snapshot[i].Quantity += c.Quantity
+			continue
+		}
+		index[k] = len(snapshot)
+		snapshot = append(snapshot, VersionCard{
+			Board:      c.Board,
+			Name:       c.Name,
+			ScryfallID: c.ScryfallID,
+			Quantity:   c.Quantity,
+		})
+	}
+
+	slices.SortFunc(snapshot, func(a, b VersionCard) int {
+		if c := strings.Compare(a.Board, b.Board); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+	return snapshot
+}
+
+// DiffVersions compares the cards of two versions of a deck. A card moved
+// from one board to another is removed from the first and added to the other.
+func DiffVersions(from, to DeckVersion) DeckDiff {
+	type key struct{ board, name string }
+
+	diff := DeckDiff{
+		DeckID:  to.DeckID,
+		From:    from,
+		To:      to,
+		Added:   []CardChange{},
+		Removed: []CardChange{},
+		Changed: []CardChange{},
+	}
+	diff.From.Cards = nil
+	diff.To.Cards = nil
+
+	before := map[key]int{}
+	for _, c := range from.Cards {
+		before[key{c.Board, c.Name}] += c.Quantity
+	}
+	after := map[key]int{}
+	for _, c := range to.Cards {
+		after[key{c.Board, c.Name}] += c.Quantity
+	}
+
+	for k, n := range after {
+		change := CardChange{Board: k.board, Name: k.name, From: before[k], To: n}
+		switch {
+		case before[k] == 0:
+			diff.Added = append(diff.Added, change)
+		case before[k] != n:
+			diff.Changed = append(diff.Changed, change)
+		}
+	}
+	for k, n := range before {
+		if _, ok := after[k]; !ok {
+			diff.Removed = append(diff.Removed, CardChange{Board: k.board, Name: k.name, From: n})
+		}
+	}
+
+	for _, changes := range [][]CardChange{diff.Added, diff.Removed, diff.Changed} {
+		slices.SortFunc(changes, func(a, b CardChange) int {
+			return cmp.Or(strings.Compare(a.Board, b.Board), strings.Compare(a.Name, b.Name))
+		})
+	}
+
+	return diff
+}
+
+// snapshotDeck keeps a copy of the deck's cards as part of a transaction. A
+// version of the deck that was already captured is left alone so only changes
+// on the service add to the history.
+func snapshotDeck(ctx context.Context, tx *sqlx.Tx, deck Deck) error {
+
+	const q = `
+	INSERT INTO deck_versions (
+		id,
+		deck_id,
+		version,
+		updated_at,
+		captured_at,
+		cards
+	) VALUES (
+		:id,
+		:deck_id,
+		:version,
+		:updated_at,
+		:captured_at,
+		:cards
+	)
+	ON CONFLICT (deck_id, version, updated_at) DO NOTHING`
+
+	v := DeckVersion{
+		ID:         uuid.New().String(),
+		DeckID:     deck.ID,
+		Version:    deck.Version,
+		UpdatedAt:  deck.UpdatedAt,
+		CapturedAt: time.Now(),
+		Cards:      versionCards(deck.Cards),
+	}
+
+	_, err := tx.NamedExecContext(ctx, q, v)
+	return err
+}
+
+// checkVersion compares a stored deck to the same deck fresh from its service.
+// The version can move without the update time so the new version's cards are
+// kept. A stored version of 0 is unknown since the deck was stored before
+// versions were. Its version is recorded without another snapshot.
+func (s *Service) checkVersion(ctx context.Context, log *slog.Logger, deck *Deck, srcDeck Deck) error {
+	switch {
+	case srcDeck.Version == deck.Version:
+		return nil
+
+	case deck.Version == 0:
+		log.Debug("recording deck version", "version", srcDeck.Version)
+		deck.Version = srcDeck.Version
+		return s.adoptVersion(ctx, *deck)
+	}
+
+	log.Info("new deck version found", "version", srcDeck.Version)
+	if err := s.ReplaceDeck(ctx, srcDeck); err != nil {
+		return err
+	}
+	deck.Version = srcDeck.Version
+	return nil
+}
+
+// versionsDue reports if the deck's version hasn't been checked recently.
+func (d Deck) versionsDue(now time.Time) bool {
+	return d.VersionsCheckedAt == nil || now.Sub(*d.VersionsCheckedAt) >= versionInterval
+}
+
+// markVersionsChecked records that the deck's version was compared to its
+// service.
+func (s *Service) markVersionsChecked(ctx context.Context, deckID string, at time.Time) error {
+	const q = `UPDATE decks SET versions_checked_at = $2 WHERE id = $1`
+
+	_, err := s.db.ExecContext(ctx, q, deckID, at)
+	return err
+}
+
+// adoptVersion records the version of a deck whose version was unknown. The
+// snapshot taken of it before versions were known is relabeled with it.
+func (s *Service) adoptVersion(ctx context.Context, deck Deck) error {
+
+	const q = `
+	UPDATE deck_versions v SET
+		version = $2
+	WHERE v.deck_id = $1
+		AND v.version = 0
+		AND v.updated_at = $3
+		AND NOT EXISTS (
+			SELECT 1
+			FROM deck_versions o
+			WHERE o.deck_id = v.deck_id
+				AND o.version = $2
+				AND o.updated_at = v.updated_at
+		)`
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := updateDeck(ctx, tx, deck); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, q, deck.ID, deck.Version, deck.UpdatedAt); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetDeckVersions lists the versions of one of the user's decks, newest first.
+func (s *Service) GetDeckVersions(ctx context.Context, user users.User, deckID string) ([]DeckVersion, error) {
+	if _, err := s.deckFormat(ctx, user, deckID); err != nil {
+		if errors.Is(err, ErrDeckNotFound) {
+			return nil, ErrDeckNotFound
+		}
+		return nil, err
+	}
+
+	const q = `
+	SELECT
+		v.id,
+		v.deck_id,
+		v.version,
+		v.updated_at,
+		v.captured_at,
+		COALESCE((
+			SELECT SUM((c->>'quantity')::INTEGER)
+			FROM jsonb_array_elements(v.cards) c
+			WHERE c->>'board' <> 'maybeboard'
+		), 0) AS size
+	FROM deck_versions v
+	WHERE v.deck_id = $1
+	ORDER BY v.captured_at DESC`
+
+	versions := []DeckVersion{}
+	err := s.db.SelectContext(ctx, &versions, q, deckID)
+	return versions, err
+}
+
+// DiffDeckVersions compares two versions of one of the user's decks. A blank
+// to is the latest version and a blank from is the version before to.
+func (s *Service) DiffDeckVersions(ctx context.Context, user users.User, deckID, from, to string) (DeckDiff, error) {
+	versions, err := s.GetDeckVersions(ctx, user, deckID)
+	if err != nil {
+		return DeckDiff{}, err
+	}
+
+	// Versions are newest first so the one before a version follows it.
+	toIdx := 0
+	if to != "" {
+		toIdx = slices.IndexFunc(versions, func(v DeckVersion) bool { return v.ID == to })
+	}
+	if toIdx < 0 || toIdx >= len(versions) {
+		return DeckDiff{}, fmt.Errorf("%w %q", ErrVersionNotFound, to)
+	}
+
+	fromIdx := toIdx + 1
+	if from != "" {
+		fromIdx = slices.IndexFunc(versions, func(v DeckVersion) bool { return v.ID == from })
+	}
+	if fromIdx < 0 {
+		return DeckDiff{}, fmt.Errorf("%w %q", ErrVersionNotFound, from)
+	}
+
+	toVersion, err := s.getDeckVersion(ctx, versions[toIdx])
+	if err != nil {
+		return DeckDiff{}, err
+	}
+
+	// The first version of a deck is compared to an empty deck.
+	fromVersion := DeckVersion{DeckID: deckID}
+	if fromIdx < len(versions) {
+		if fromVersion, err = s.getDeckVersion(ctx, versions[fromIdx]); err != nil {
+			return DeckDiff{}, err
+		}
+	}
+
+	return DiffVersions(fromVersion, toVersion), nil
+}
+
+// getDeckVersion loads the cards of a version from the list of versions.
+func (s *Service) getDeckVersion(ctx context.Context, v DeckVersion) (DeckVersion, error) {
+	const q = `SELECT cards FROM deck_versions WHERE id = $1`
+
+	if err := s.db.GetContext(ctx, &v.Cards, q, v.ID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return DeckVersion{}, fmt.Errorf("%w %q", ErrVersionNotFound, v.ID)
+		}
+		return DeckVersion{}, err
+	}
+	return v, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// DB Methods for Storing
+////////////////////////////////////////////////////////////////////////////////
+
+// Scan implements the Scanner interface for VersionCards.
+func (vc *VersionCards) Scan(v interface{}) error {
+	if v == nil {
+		return nil
+	}
+	b, ok := v.([]byte)
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(b, vc)
+}
+
+// Value implements the Valuer interface for VersionCards.
+func (vc VersionCards) Value() (driver.Value, error) {
+	if vc == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(vc)
+}
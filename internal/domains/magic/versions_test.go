@@ -0,0 +1,99 @@
+package magic
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestVersionCards(t *testing.T) {
+	cards := []DeckCard{
+		{Board: BoardMainboard, Quantity: 1, Name: "Sol Ring", ScryfallID: "ring-c21"},
+		{Board: BoardCommanders, Quantity: 1, Name: "Arahbo, Roar of the World", ScryfallID: "arahbo"},
+		{Board: BoardMainboard, Quantity: 1, Name: "Sol Ring", ScryfallID: "ring-cmm"},
+		{Board: BoardMaybeboard, Quantity: 1, Name: "Sol Ring", ScryfallID: "ring-c21"},
+	}
+
+	want := VersionCards{
+		{Board: BoardCommanders, Name: "Arahbo, Roar of the World", ScryfallID: "arahbo", Quantity: 1},
+		{Board: BoardMainboard, Name: "Sol Ring", ScryfallID: "ring-c21", Quantity: 2},
+		{Board: BoardMaybeboard, Name: "Sol Ring", ScryfallID: "ring-c21", Quantity: 1},
+	}
+
+	if diff := cmp.Diff(want, versionCards(cards)); diff != "" {
+		t.Errorf("wrong snapshot:\n%s", diff)
+	}
+}
+
+func TestDiffVersions(t *testing.T) {
+	from := DeckVersion{
+		ID:     "v1",
+		DeckID: "deck",
+		Cards: VersionCards{
+			{Board: BoardCommanders, Name: "Arahbo, Roar of the World", Quantity: 1},
+			{Board: BoardMainboard, Name: "Plains", Quantity: 30},
+			{Board: BoardMainboard, Name: "Sol Ring", Quantity: 1},
+			{Board: BoardMainboard, Name: "Swords to Plowshares", Quantity: 1},
+			{Board: BoardMaybeboard, Name: "Path to Exile", Quantity: 1},
+		},
+	}
+	to := DeckVersion{
+		ID:      "v2",
+		DeckID:  "deck",
+		Version: 1,
+		Cards: VersionCards{
+			{Board: BoardCommanders, Name: "Arahbo, Roar of the World", Quantity: 1},
+			{Board: BoardMainboard, Name: "Forest", Quantity: 3},
+			{Board: BoardMainboard, Name: "Path to Exile", Quantity: 1},
+			{Board: BoardMainboard, Name: "Plains", Quantity: 27},
+			{Board: BoardMainboard, Name: "Sol Ring", Quantity: 1},
+		},
+	}
+
+	want := DeckDiff{
+		DeckID: "deck",
+		From:   DeckVersion{ID: "v1", DeckID: "deck"},
+		To:     DeckVersion{ID: "v2", DeckID: "deck", Version: 1},
+		Added: []CardChange{
+			{Board: BoardMainboard, Name: "Forest", To: 3},
+			{Board: BoardMainboard, Name: "Path to Exile", To: 1},
+		},
+		Removed: []CardChange{
+			{Board: BoardMainboard, Name: "Swords to Plowshares", From: 1},
+			{Board: BoardMaybeboard, Name: "Path to Exile", From: 1},
+		},
+		Changed: []CardChange{
+			{Board: BoardMainboard, Name: "Plains", From: 30, To: 27},
+		},
+	}
+
+	if diff := cmp.Diff(want, DiffVersions(from, to)); diff != "" {
+		t.Errorf("wrong diff:\n%s", diff)
+	}
+}
+
+func TestVersionsDue(t *testing.T) {
+	now := time.Date(2024, 11, 2, 12, 0, 0, 0, time.UTC)
+	recent := now.Add(-time.Minute)
+	old := now.Add(-versionInterval)
+
+	tests := []struct {
+		name    string
+		checked *time.Time
+		want    bool
+	}{
+		{name: "never checked", checked: nil, want: true},
+		{name: "checked recently", checked: &recent, want: false},
+		{name: "checked an interval ago", checked: &old, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := Deck{VersionsCheckedAt: tt.checked}
+			if got := d.versionsDue(now); got != tt.want {
+				t.Errorf("versionsDue should be %t but was %t", tt.want, got)
+			}
+		})
+	}
+}
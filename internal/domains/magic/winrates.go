@@ -0,0 +1,266 @@
+package magic
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/jcbwlkr/deck-stats/internal/domains/users"
+)
+
+// wilsonZ is the z score for the 95% confidence intervals on win rates.
+const wilsonZ = 1.96
+
+// WinRate is how one group of games went. Groups are sorted by Lower, the
+// pessimistic end of the confidence interval, so a deck that is 2-0 doesn't
+// rank above one that is 30-15.
+type WinRate struct {
+	Key          string  `json:"key"`
+	Name         string  `json:"name"`
+	Games        int     `json:"games"`
+	Wins         int     `json:"wins"`
+	Losses       int     `json:"losses"`
+	Draws        int     `json:"draws"`
+	WinRate      float64 `json:"win_rate"`
+	Lower        float64 `json:"lower"`
+	Upper        float64 `json:"upper"`
+	AverageTurns float64 `json:"average_turns"`
+
+	turns      int // Sum of recorded turns
+	turnsGames int // Games with turns recorded
+}
+
+// GameStats is the user's win rates grouped a few different ways.
+type GameStats struct {
+	Games           int       `json:"games"`
+	Decks           []WinRate `json:"decks"`
+	Commanders      []WinRate `json:"commanders"`
+	ColorIdentities []WinRate `json:"color_identities"`
+	Formats         []WinRate `json:"formats"`
+
+	// Seats is the win rate of every player by turn order position across
+	// the games where it was recorded. Seats are in position order.
+	Seats []WinRate `json:"seats"`
+}
+
+// GameRecord is a game along with the deck it was played with.
+type GameRecord struct {
+	Result        string        `db:"result"`
+	Turns         int           `db:"turns"`
+	Format        string        `db:"format"`
+	DeckID        string        `db:"deck_id"`
+	DeckName      string        `db:"deck_name"`
+	ColorIdentity ColorIdentity `db:"color_identity"`
+	Leaders       Leaders       `db:"leaders"`
+}
+
+// SeatRecord is one seat of a game that recorded turn order.
+type SeatRecord struct {
+	Position int    `db:"position"`
+	Won      bool   `db:"won"`
+	Result   string `db:"result"` // Result of the game for the user
+	Turns    int    `db:"turns"`
+}
+
+// commanders is the key and name for the deck's commanders. Partners are
+// grouped together as one team.
+func (r GameRecord) commanders() (string, string) {
+	var ids, names []string
+	for _, c := range r.Leaders.Commanders {
+		ids = append(ids, c.ID)
+		names = append(names, c.Name)
+	}
+	slices.Sort(ids)
+	slices.Sort(names)
+	return strings.Join(ids, "+"), strings.Join(names, " + ")
+}
+
+// add counts one game towards the win rate.
+func (w *WinRate) add(r GameRecord) {
+	w.Games++
+	switch r.Result {
+	case ResultWin:
+		w.Wins++
+	case ResultLoss:
+		w.Losses++
+	case ResultDraw:
+		w.Draws++
+	}
+	if r.Turns > 0 {
+		w.turns += r.Turns
+		w.turnsGames++
+	}
+}
+
+// finish works out the rates once every game has been added.
+func (w *WinRate) finish() {
+	w.WinRate = round(float64(w.Wins) / float64(w.Games))
+	w.Lower, w.Upper = wilson(w.Wins, w.Games)
+	if w.turnsGames > 0 {
+		w.AverageTurns = round(float64(w.turns) / float64(w.turnsGames))
+	}
+}
+
+// ComputeGameStats groups the games by deck, commanders, color identity and
+// format. Decks without commanders are left out of the commander group and
+// identities without a name are left out of the color identity group.
+func ComputeGameStats(records []GameRecord) GameStats {
+	decks := map[string]*WinRate{}
+	commanders := map[string]*WinRate{}
+	identities := map[string]*WinRate{}
+	formats := map[string]*WinRate{}
+
+	count := func(group map[string]*WinRate, key, name string, r GameRecord) {
+		w, ok := group[key]
+		if !ok {
+			w = &WinRate{Key: key, Name: name}
+			group[key] = w
+		}
+		w.add(r)
+	}
+
+	for _, r := range records {
+		count(decks, r.DeckID, r.DeckName, r)
+
+		if key, name := r.commanders(); key != "" {
+			count(commanders, key, name, r)
+		}
+		if name := r.ColorIdentity.Name(); name != "" {
+			count(identities, name, name, r)
+		}
+		count(formats, r.Format, r.Format, r)
+	}
+
+	return GameStats{
+		Games:           len(records),
+		Decks:           sortedWinRates(decks),
+		Commanders:      sortedWinRates(commanders),
+		ColorIdentities: sortedWinRates(identities),
+		Formats:         sortedWinRates(formats),
+	}
+}
+
+// ComputeSeatStats works out the win rate of each turn order position. A seat
+// that didn't win lost unless the whole game was a draw.
+func ComputeSeatStats(seats []SeatRecord) []WinRate {
+	positions := map[int]*WinRate{}
+
+	for _, s := range seats {
+		w, ok := positions[s.Position]
+		if !ok {
+			w = &WinRate{
+				Key:  strconv.Itoa(s.Position),
+				Name: fmt.Sprintf("Seat %d", s.Position),
+			}
+			positions[s.Position] = w
+		}
+
+		r := GameRecord{Result: ResultLoss, Turns: s.Turns}
+		switch {
+		case s.Won:
+			r.Result = ResultWin
+		case s.Result == ResultDraw:
+			r.Result = ResultDraw
+		}
+		w.add(r)
+	}
+
+	rates := make([]WinRate, 0, len(positions))
+	for _, pos := range slices.Sorted(maps.Keys(positions)) {
+		w := positions[pos]
+		w.finish()
+		rates = append(rates, *w)
+	}
+	return rates
+}
+
+// sortedWinRates finishes every win rate in the group and sorts them best
+// first by the lower end of their confidence intervals.
+func sortedWinRates(group map[string]*WinRate) []WinRate {
+	rates := make([]WinRate, 0, len(group))
+	for _, w := range group {
+		w.finish()
+		rates = append(rates, *w)
+	}
+	slices.SortFunc(rates, func(a, b WinRate) int {
+		if c := cmp.Compare(b.Lower, a.Lower); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(b.Games, a.Games); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+	return rates
+}
+
+// wilson is the Wilson score interval for the win rate. It behaves much better
+// than the normal approximation for the small samples a single deck has.
+func wilson(wins, games int) (float64, float64) {
+	if games == 0 {
+		return 0, 0
+	}
+
+	n := float64(games)
+	p := float64(wins) / n
+	z2 := wilsonZ * wilsonZ
+
+	center := p + z2/(2*n)
+	margin := wilsonZ * math.Sqrt(p*(1-p)/n+z2/(4*n*n))
+	denom := 1 + z2/n
+
+	return round((center - margin) / denom), round((center + margin) / denom)
+}
+
+// round rounds to 4 decimal places which is plenty for a percentage.
+func round(f float64) float64 {
+	return math.Round(f*10000) / 10000
+}
+
+// GetGameStats reports the win rates of the user's games.
+func (s *Service) GetGameStats(ctx context.Context, user users.User) (GameStats, error) {
+
+	const q = `
+	SELECT
+		g.result,
+		g.turns,
+		g.format,
+		d.id AS deck_id,
+		d.name AS deck_name,
+		d.color_identity,
+		d.leaders
+	FROM games g
+	JOIN decks d ON d.id = g.deck_id
+	WHERE g.user_id = $1`
+
+	records := []GameRecord{}
+	if err := s.db.SelectContext(ctx, &records, q, user.ID); err != nil {
+		return GameStats{}, err
+	}
+
+	const seatsQ = `
+	SELECT
+		s.position,
+		s.won,
+		g.result,
+		g.turns
+	FROM game_seats s
+	JOIN games g ON g.id = s.game_id
+	WHERE g.user_id = $1
+		AND s.position > 0`
+
+	seats := []SeatRecord{}
+	if err := s.db.SelectContext(ctx, &seats, seatsQ, user.ID); err != nil {
+		return GameStats{}, err
+	}
+
+	stats := ComputeGameStats(records)
+	stats.Seats = ComputeSeatStats(seats)
+
+	return stats, nil
+}
@@ -0,0 +1,133 @@
+package magic
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestWilson(t *testing.T) {
+	tests := []struct {
+		wins, games  int
+		lower, upper float64
+	}{
+		{0, 0, 0, 0},
+		{2, 2, 0.3424, 1},
+		{30, 45, 0.5207, 0.7864},
+		{0, 10, 0, 0.2775},
+	}
+
+	for _, tt := range tests {
+		lower, upper := wilson(tt.wins, tt.games)
+		if lower != tt.lower || upper != tt.upper {
+			t.Errorf("wilson(%d, %d) should be (%v, %v) but got (%v, %v)", tt.wins, tt.games, tt.lower, tt.upper, lower, upper)
+		}
+	}
+}
+
+func TestComputeGameStats(t *testing.T) {
+	cats := GameRecord{
+		Format:        "commander",
+		DeckID:        "cats",
+		DeckName:      "Cats!",
+		ColorIdentity: Selesnya,
+		Leaders: Leaders{
+			Commanders: []Card{{ID: "arahbo", Name: "Arahbo, Roar of the World"}},
+		},
+	}
+	vials := GameRecord{
+		Format:        "commander",
+		DeckID:        "vials",
+		DeckName:      "1,000 Smashed Vials",
+		ColorIdentity: Grixis,
+		Leaders: Leaders{
+			Commanders: []Card{
+				{ID: "vial", Name: "Vial Smasher the Fierce"},
+				{ID: "sakashima", Name: "Sakashima of a Thousand Faces"},
+			},
+		},
+	}
+	burn := GameRecord{
+		Format:        "modern",
+		DeckID:        "burn",
+		DeckName:      "Burn",
+		ColorIdentity: MonoRed,
+	}
+
+	game := func(r GameRecord, result string, turns int) GameRecord {
+		r.Result = result
+		r.Turns = turns
+		return r
+	}
+
+	// Vials is 2-0 but cats has a much bigger sample at 6-3.
+	var records []GameRecord
+	records = append(records, game(vials, ResultWin, 8), game(vials, ResultWin, 0))
+	for range 6 {
+		records = append(records, game(cats, ResultWin, 10))
+	}
+	records = append(records, game(cats, ResultLoss, 7), game(cats, ResultLoss, 7), game(cats, ResultDraw, 0))
+	records = append(records, game(burn, ResultLoss, 4))
+
+	stats := ComputeGameStats(records)
+
+	opts := cmpopts.IgnoreUnexported(WinRate{})
+
+	wantDecks := []WinRate{
+		{Key: "cats", Name: "Cats!", Games: 9, Wins: 6, Losses: 2, Draws: 1, WinRate: 0.6667, Lower: 0.3542, Upper: 0.8794, AverageTurns: 9.25},
+		{Key: "vials", Name: "1,000 Smashed Vials", Games: 2, Wins: 2, WinRate: 1, Lower: 0.3424, Upper: 1, AverageTurns: 8},
+		{Key: "burn", Name: "Burn", Games: 1, Losses: 1, WinRate: 0, Lower: 0, Upper: 0.7935, AverageTurns: 4},
+	}
+	if diff := cmp.Diff(wantDecks, stats.Decks, opts); diff != "" {
+		t.Errorf("wrong deck win rates:\n%s", diff)
+	}
+
+	var commanders []string
+	for _, w := range stats.Commanders {
+		commanders = append(commanders, w.Name)
+	}
+	wantCommanders := []string{"Arahbo, Roar of the World", "Sakashima of a Thousand Faces + Vial Smasher the Fierce"}
+	if diff := cmp.Diff(wantCommanders, commanders); diff != "" {
+		t.Errorf("wrong commanders:\n%s", diff)
+	}
+
+	var formats []string
+	for _, w := range stats.Formats {
+		formats = append(formats, w.Key)
+	}
+	if diff := cmp.Diff([]string{"commander", "modern"}, formats); diff != "" {
+		t.Errorf("wrong formats:\n%s", diff)
+	}
+
+	if got, want := stats.Formats[0].Games, 11; got != want {
+		t.Errorf("commander should have %d games but had %d", want, got)
+	}
+	if got, want := len(stats.ColorIdentities), 3; got != want {
+		t.Errorf("should have %d color identities but had %d", want, got)
+	}
+}
+
+func TestComputeSeatStats(t *testing.T) {
+	seats := []SeatRecord{
+		{Position: 1, Won: true, Result: ResultLoss, Turns: 8},
+		{Position: 2, Result: ResultLoss, Turns: 8},
+		{Position: 1, Result: ResultDraw},
+		{Position: 2, Result: ResultDraw},
+		{Position: 2, Won: true, Result: ResultWin, Turns: 10},
+		{Position: 1, Result: ResultWin, Turns: 10},
+	}
+
+	var got []WinRate
+	for _, w := range ComputeSeatStats(seats) {
+		got = append(got, WinRate{Key: w.Key, Name: w.Name, Games: w.Games, Wins: w.Wins, Losses: w.Losses, Draws: w.Draws, AverageTurns: w.AverageTurns})
+	}
+
+	want := []WinRate{
+		{Key: "1", Name: "Seat 1", Games: 3, Wins: 1, Losses: 1, Draws: 1, AverageTurns: 9},
+		{Key: "2", Name: "Seat 2", Games: 3, Wins: 1, Losses: 1, Draws: 1, AverageTurns: 9},
+	}
+	if diff := cmp.Diff(want, got, cmpopts.IgnoreUnexported(WinRate{})); diff != "" {
+		t.Errorf("wrong seat win rates:\n%s", diff)
+	}
+}
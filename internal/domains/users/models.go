@@ -9,12 +9,22 @@ const (
 	RoleUser = "USER"
 )
 
+// Values for Account.RefreshStatus. Failed refreshes have the error appended
+// to RefreshStatusFailed.
+const (
+	RefreshStatusPending     = "pending"
+	RefreshStatusCompleted   = "completed"
+	RefreshStatusInterrupted = "interrupted"
+	RefreshStatusFailed      = "failed: "
+)
+
 var (
 	ErrBlankPassword       = errors.New("password is blank")
 	ErrPasswordsDoNotMatch = errors.New("passwords do not match")
 	ErrUsernameRegistered  = errors.New("username is already registered")
 	ErrUserNotFound        = errors.New("username is not registered")
 	ErrPasswordInvalid     = errors.New("wrong password")
+	ErrAccountNotFound     = errors.New("account not found")
 )
 
 type User struct {
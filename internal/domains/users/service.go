@@ -108,18 +108,46 @@ func (s *Service) GetUserByUsername(ctx context.Context, username string) (*User
 func (s *Service) GetAccount(ctx context.Context, id, userID string) (Account, error) {
 	const q = `
 	SELECT
-		id, service, username
+		id,
+		user_id,
+		service,
+		username,
+		refresh_started_at,
+		refresh_active_at,
+		refresh_completed_at,
+		refresh_status
 	FROM user_accounts
 	WHERE id = $1
 		AND user_id = $2`
 
-	// TODO(jlw) this should include the refresh columns
-
 	var a Account
 	err := s.db.GetContext(ctx, &a, q, id, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return a, ErrAccountNotFound
+	}
 	return a, err
 }
 
+func (s *Service) ListAccounts(ctx context.Context, userID string) ([]Account, error) {
+	const q = `
+	SELECT
+		id,
+		user_id,
+		service,
+		username,
+		refresh_started_at,
+		refresh_active_at,
+		refresh_completed_at,
+		refresh_status
+	FROM user_accounts
+	WHERE user_id = $1
+	ORDER BY service, username`
+
+	accounts := []Account{}
+	err := s.db.SelectContext(ctx, &accounts, q, userID)
+	return accounts, err
+}
+
 func (s *Service) CreateAccount(ctx context.Context, userID string, na NewAccount) (Account, error) {
 	const q = `
 	INSERT INTO user_accounts
@@ -152,3 +180,25 @@ func (s *Service) UpdateAccount(ctx context.Context, account Account) error {
 	_, err := s.db.NamedExecContext(ctx, q, account)
 	return err
 }
+
+// DeleteAccount removes the account as part of tx so whatever was synced from
+// it can be cleaned up in the same transaction.
+func (s *Service) DeleteAccount(ctx context.Context, tx *sqlx.Tx, id, userID string) error {
+	const q = `
+	DELETE FROM user_accounts
+	WHERE id = $1
+		AND user_id = $2`
+
+	res, err := tx.ExecContext(ctx, q, id, userID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrAccountNotFound
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/jcbwlkr/deck-stats/internal/auth"
+	"github.com/jcbwlkr/deck-stats/internal/domains/magic"
+)
+
+type CardHandlers struct {
+	svc *magic.Service
+}
+
+func (h *CardHandlers) SearchCards(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user, ok := auth.User(ctx)
+	if !ok {
+		http.Error(w, "system error", http.StatusInternalServerError)
+		return
+	}
+
+	query := r.URL.Query()
+	search := magic.CardSearch{
+		Name:      query.Get("name"),
+		Text:      query.Get("text"),
+		Type:      query.Get("type"),
+		ManaValue: query.Get("mv"),
+		Color:     query.Get("color"),
+		Rarity:    query.Get("rarity"),
+	}
+
+	cards, err := h.svc.SearchDeckCards(ctx, user, search)
+	if err != nil {
+		if errors.Is(err, magic.ErrInvalidSearch) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		slog.ErrorContext(ctx, "could not search cards", "error", err)
+		http.Error(w, "system error", http.StatusInternalServerError)
+		return
+	}
+
+	response := struct {
+		Cards []magic.CardMatch `json:"cards"`
+	}{
+		Cards: cards,
+	}
+	json.NewEncoder(w).Encode(response)
+}
@@ -1,9 +1,15 @@
 package handlers
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
+	"mime"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/jcbwlkr/deck-stats/internal/auth"
 	"github.com/jcbwlkr/deck-stats/internal/domains/magic"
@@ -24,7 +30,25 @@ func (h *DeckHandlers) GetDecks(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	decks, err := h.svc.GetDecksForUser(ctx, user)
+	var filter magic.DeckFilter
+	if v := r.URL.Query().Get("include_deleted"); v != "" {
+		var err error
+		filter.IncludeDeleted, err = strconv.ParseBool(v)
+		if err != nil {
+			http.Error(w, "include_deleted must be true or false", http.StatusBadRequest)
+			return
+		}
+	}
+	if v := r.URL.Query().Get("illegal"); v != "" {
+		var err error
+		filter.Illegal, err = strconv.ParseBool(v)
+		if err != nil {
+			http.Error(w, "illegal must be true or false", http.StatusBadRequest)
+			return
+		}
+	}
+
+	decks, err := h.svc.GetDecksForUser(ctx, user, filter)
 	if err != nil {
 		slog.ErrorContext(ctx, "could not list decks", "error", err)
 		http.Error(w, "system error", http.StatusInternalServerError)
@@ -39,6 +63,401 @@ func (h *DeckHandlers) GetDecks(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+func (h *DeckHandlers) ImportDeck(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user, ok := auth.User(ctx)
+	if !ok {
+		http.Error(w, "system error", http.StatusInternalServerError)
+		return
+	}
+
+	var input magic.ImportDeck
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		slog.WarnContext(ctx, "could not decode input", "error", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	deck, err := h.svc.ImportDeck(ctx, user, input)
+	if err != nil {
+		var notFound magic.CardsNotFoundError
+		if errors.Is(err, magic.ErrInvalidDecklist) ||
+			errors.Is(err, magic.ErrDeckNameRequired) ||
+			errors.As(err, &notFound) {
+			slog.WarnContext(ctx, "could not import deck", "error", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		slog.ErrorContext(ctx, "could not import deck", "error", err)
+		http.Error(w, "system error", http.StatusInternalServerError)
+		return
+	}
+
+	response := struct {
+		Deck magic.Deck `json:"deck"`
+	}{
+		Deck: deck,
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *DeckHandlers) GetDeckStats(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user, ok := auth.User(ctx)
+	if !ok {
+		http.Error(w, "system error", http.StatusInternalServerError)
+		return
+	}
+
+	deck, err := h.svc.GetDeck(ctx, user, r.PathValue("id"))
+	if err != nil {
+		if errors.Is(err, magic.ErrDeckNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		slog.ErrorContext(ctx, "could not get deck", "error", err)
+		http.Error(w, "system error", http.StatusInternalServerError)
+		return
+	}
+
+	response := struct {
+		Stats magic.DeckStats `json:"stats"`
+	}{
+		Stats: magic.ComputeStats(deck),
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *DeckHandlers) ValidateDeck(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user, ok := auth.User(ctx)
+	if !ok {
+		http.Error(w, "system error", http.StatusInternalServerError)
+		return
+	}
+
+	deck, err := h.svc.GetDeck(ctx, user, r.PathValue("id"))
+	if err != nil {
+		if errors.Is(err, magic.ErrDeckNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		slog.ErrorContext(ctx, "could not get deck", "error", err)
+		http.Error(w, "system error", http.StatusInternalServerError)
+		return
+	}
+
+	response := struct {
+		Validation magic.DeckValidation `json:"validation"`
+	}{
+		Validation: magic.ValidateDeck(deck),
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *DeckHandlers) GetDeckValue(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user, ok := auth.User(ctx)
+	if !ok {
+		http.Error(w, "system error", http.StatusInternalServerError)
+		return
+	}
+
+	value, err := h.svc.GetDeckValue(ctx, user, r.PathValue("id"), r.URL.Query().Get("vendor"))
+	if err != nil {
+		switch {
+		case errors.Is(err, magic.ErrDeckNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		case errors.Is(err, magic.ErrUnknownVendor):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		slog.ErrorContext(ctx, "could not get deck value", "error", err)
+		http.Error(w, "system error", http.StatusInternalServerError)
+		return
+	}
+
+	response := struct {
+		Value magic.DeckValue `json:"value"`
+	}{
+		Value: value,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *DeckHandlers) ExportDeck(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user, ok := auth.User(ctx)
+	if !ok {
+		http.Error(w, "system error", http.StatusInternalServerError)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	contentType, ok := magic.ExportContentTypes[format]
+	if !ok {
+		http.Error(w, "format must be one of arena, mtgo, csv or json", http.StatusBadRequest)
+		return
+	}
+
+	deck, err := h.svc.GetDeck(ctx, user, r.PathValue("id"))
+	if err != nil {
+		if errors.Is(err, magic.ErrDeckNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		slog.ErrorContext(ctx, "could not get deck", "error", err)
+		http.Error(w, "system error", http.StatusInternalServerError)
+		return
+	}
+
+	// Export to a buffer so a failure can still be reported as an error.
+	var buf bytes.Buffer
+	if err := magic.ExportDeck(&buf, deck, format); err != nil {
+		slog.ErrorContext(ctx, "could not export deck", "error", err)
+		http.Error(w, "system error", http.StatusInternalServerError)
+		return
+	}
+
+	ext := format
+	if format == magic.ExportArena || format == magic.ExportMTGO {
+		ext = "txt"
+	}
+	disposition := mime.FormatMediaType("attachment", map[string]string{
+		"filename": fmt.Sprintf("%s.%s", deck.Name, ext),
+	})
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", disposition)
+	w.Write(buf.Bytes())
+}
+
+func (h *DeckHandlers) GetColorReport(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user, ok := auth.User(ctx)
+	if !ok {
+		http.Error(w, "system error", http.StatusInternalServerError)
+		return
+	}
+
+	report, err := h.svc.GetColorReport(ctx, user, r.URL.Query().Get("format"))
+	if err != nil {
+		slog.ErrorContext(ctx, "could not build color report", "error", err)
+		http.Error(w, "system error", http.StatusInternalServerError)
+		return
+	}
+
+	response := struct {
+		Report magic.ColorReport `json:"report"`
+	}{
+		Report: report,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *DeckHandlers) GetArchetypeStats(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user, ok := auth.User(ctx)
+	if !ok {
+		http.Error(w, "system error", http.StatusInternalServerError)
+		return
+	}
+
+	archetypes, err := h.svc.GetArchetypeStats(ctx, user)
+	if err != nil {
+		slog.ErrorContext(ctx, "could not build archetype stats", "error", err)
+		http.Error(w, "system error", http.StatusInternalServerError)
+		return
+	}
+
+	response := struct {
+		Archetypes []magic.ArchetypeStats `json:"archetypes"`
+	}{
+		Archetypes: archetypes,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *DeckHandlers) GetLeaderboard(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user, ok := auth.User(ctx)
+	if !ok {
+		http.Error(w, "system error", http.StatusInternalServerError)
+		return
+	}
+
+	ratings, err := h.svc.GetLeaderboard(ctx, user)
+	if err != nil {
+		slog.ErrorContext(ctx, "could not get leaderboard", "error", err)
+		http.Error(w, "system error", http.StatusInternalServerError)
+		return
+	}
+
+	response := struct {
+		Ratings []magic.DeckRating `json:"ratings"`
+	}{
+		Ratings: ratings,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *DeckHandlers) GetRatingHistory(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user, ok := auth.User(ctx)
+	if !ok {
+		http.Error(w, "system error", http.StatusInternalServerError)
+		return
+	}
+
+	history, err := h.svc.GetRatingHistory(ctx, user, r.PathValue("id"))
+	if err != nil {
+		if errors.Is(err, magic.ErrDeckNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		slog.ErrorContext(ctx, "could not get rating history", "error", err)
+		http.Error(w, "system error", http.StatusInternalServerError)
+		return
+	}
+
+	response := struct {
+		History []magic.RatingChange `json:"history"`
+	}{
+		History: history,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *DeckHandlers) GetDeckVersions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user, ok := auth.User(ctx)
+	if !ok {
+		http.Error(w, "system error", http.StatusInternalServerError)
+		return
+	}
+
+	versions, err := h.svc.GetDeckVersions(ctx, user, r.PathValue("id"))
+	if err != nil {
+		if errors.Is(err, magic.ErrDeckNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		slog.ErrorContext(ctx, "could not list deck versions", "error", err)
+		http.Error(w, "system error", http.StatusInternalServerError)
+		return
+	}
+
+	response := struct {
+		Versions []magic.DeckVersion `json:"versions"`
+	}{
+		Versions: versions,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *DeckHandlers) DiffDeckVersions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user, ok := auth.User(ctx)
+	if !ok {
+		http.Error(w, "system error", http.StatusInternalServerError)
+		return
+	}
+
+	query := r.URL.Query()
+	diff, err := h.svc.DiffDeckVersions(ctx, user, r.PathValue("id"), query.Get("from"), query.Get("to"))
+	if err != nil {
+		if errors.Is(err, magic.ErrDeckNotFound) || errors.Is(err, magic.ErrVersionNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		slog.ErrorContext(ctx, "could not diff deck versions", "error", err)
+		http.Error(w, "system error", http.StatusInternalServerError)
+		return
+	}
+
+	response := struct {
+		Diff magic.DeckDiff `json:"diff"`
+	}{
+		Diff: diff,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *DeckHandlers) GetSimilarDecks(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user, ok := auth.User(ctx)
+	if !ok {
+		http.Error(w, "system error", http.StatusInternalServerError)
+		return
+	}
+
+	limit := magic.DefaultSimilarPairs
+	if v := r.URL.Query().Get("limit"); v != "" {
+		var err error
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit < 1 {
+			http.Error(w, "limit must be a positive number", http.StatusBadRequest)
+			return
+		}
+	}
+
+	pairs, err := h.svc.GetSimilarDecks(ctx, user, limit)
+	if err != nil {
+		slog.ErrorContext(ctx, "could not compare decks", "error", err)
+		http.Error(w, "system error", http.StatusInternalServerError)
+		return
+	}
+
+	response := struct {
+		Pairs []magic.DeckSimilarity `json:"pairs"`
+	}{
+		Pairs: pairs,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *DeckHandlers) GetDeckOverlap(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user, ok := auth.User(ctx)
+	if !ok {
+		http.Error(w, "system error", http.StatusInternalServerError)
+		return
+	}
+
+	overlap, err := h.svc.GetDeckOverlap(ctx, user, r.PathValue("id"), r.PathValue("other"))
+	if err != nil {
+		if errors.Is(err, magic.ErrDeckNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		slog.ErrorContext(ctx, "could not compare decks", "error", err)
+		http.Error(w, "system error", http.StatusInternalServerError)
+		return
+	}
+
+	response := struct {
+		Overlap magic.DeckOverlap `json:"overlap"`
+	}{
+		Overlap: overlap,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
 func (h *DeckHandlers) CreateAccount(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
@@ -55,8 +474,13 @@ func (h *DeckHandlers) CreateAccount(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	account, err := h.userSvc.CreateAccount(ctx, user.ID, input)
+	account, err := h.svc.CreateAccount(ctx, user, input)
 	if err != nil {
+		if errors.Is(err, magic.ErrUnknownService) {
+			slog.WarnContext(ctx, "could not create account for user", "error", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		slog.ErrorContext(ctx, "could not create account for user", "error", err)
 		http.Error(w, "system error", http.StatusInternalServerError)
 		return
@@ -70,6 +494,91 @@ func (h *DeckHandlers) CreateAccount(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+func (h *DeckHandlers) GetAccounts(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user, ok := auth.User(ctx)
+	if !ok {
+		http.Error(w, "system error", http.StatusInternalServerError)
+		return
+	}
+
+	accounts, err := h.userSvc.ListAccounts(ctx, user.ID)
+	if err != nil {
+		slog.ErrorContext(ctx, "could not list accounts", "error", err)
+		http.Error(w, "system error", http.StatusInternalServerError)
+		return
+	}
+
+	response := struct {
+		Accounts []users.Account `json:"accounts"`
+	}{
+		Accounts: accounts,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *DeckHandlers) GetAccount(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user, ok := auth.User(ctx)
+	if !ok {
+		http.Error(w, "system error", http.StatusInternalServerError)
+		return
+	}
+
+	account, err := h.userSvc.GetAccount(ctx, r.PathValue("id"), user.ID)
+	if err != nil {
+		if errors.Is(err, users.ErrAccountNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		slog.ErrorContext(ctx, "could not get account", "error", err)
+		http.Error(w, "system error", http.StatusInternalServerError)
+		return
+	}
+
+	response := struct {
+		Account users.Account `json:"account"`
+	}{
+		Account: account,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *DeckHandlers) DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user, ok := auth.User(ctx)
+	if !ok {
+		http.Error(w, "system error", http.StatusInternalServerError)
+		return
+	}
+
+	account, err := h.userSvc.GetAccount(ctx, r.PathValue("id"), user.ID)
+	if err != nil {
+		if errors.Is(err, users.ErrAccountNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		slog.ErrorContext(ctx, "could not get account", "error", err)
+		http.Error(w, "system error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.svc.DeleteAccount(ctx, user, account); err != nil {
+		if errors.Is(err, users.ErrAccountNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		slog.ErrorContext(ctx, "could not delete account", "error", err)
+		http.Error(w, "system error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
 func (h *DeckHandlers) RefreshAccount(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
@@ -83,10 +592,105 @@ func (h *DeckHandlers) RefreshAccount(w http.ResponseWriter, r *http.Request) {
 
 	account, err := h.userSvc.GetAccount(ctx, accountID, user.ID)
 	if err != nil {
+		if errors.Is(err, users.ErrAccountNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
 		slog.ErrorContext(ctx, "could not find account for user", "error", err)
 		http.Error(w, "system error", http.StatusInternalServerError)
 		return
 	}
 
-	h.svc.RefreshDecks(user, account)
+	job, err := h.svc.RefreshDecks(ctx, user, account)
+	if err != nil {
+		slog.ErrorContext(ctx, "could not queue refresh", "error", err)
+		http.Error(w, "system error", http.StatusInternalServerError)
+		return
+	}
+
+	response := struct {
+		Job magic.RefreshJob `json:"job"`
+	}{
+		Job: job,
+	}
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(response)
+}
+
+// RefreshEvents streams progress of the account's refreshes as Server-Sent
+// Events until the client disconnects.
+func (h *DeckHandlers) RefreshEvents(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user, ok := auth.User(ctx)
+	if !ok {
+		http.Error(w, "system error", http.StatusInternalServerError)
+		return
+	}
+
+	account, err := h.userSvc.GetAccount(ctx, r.PathValue("id"), user.ID)
+	if err != nil {
+		if errors.Is(err, users.ErrAccountNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		slog.ErrorContext(ctx, "could not get account", "error", err)
+		http.Error(w, "system error", http.StatusInternalServerError)
+		return
+	}
+
+	// Subscribe before sending the current status so nothing is missed.
+	events, unsubscribe := h.svc.SubscribeRefresh(account.ID)
+	defer unsubscribe()
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	if err := writeEvent(w, "status", account); err != nil {
+		return
+	}
+	if err := rc.Flush(); err != nil {
+		slog.ErrorContext(ctx, "could not flush events", "error", err)
+		return
+	}
+
+	keepAlive := time.NewTicker(15 * time.Second)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+
+		case ev, ok := <-events:
+			if !ok {
+				return
+			}
+			if err := writeEvent(w, ev.Type, ev); err != nil {
+				return
+			}
+		}
+
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// writeEvent writes v as JSON in a single Server-Sent Event.
+func writeEvent(w http.ResponseWriter, event string, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, b)
+	return err
 }
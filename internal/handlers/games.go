@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/jcbwlkr/deck-stats/internal/auth"
+	"github.com/jcbwlkr/deck-stats/internal/domains/magic"
+)
+
+type GameHandlers struct {
+	svc *magic.Service
+}
+
+func (h *GameHandlers) CreateGame(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user, ok := auth.User(ctx)
+	if !ok {
+		http.Error(w, "system error", http.StatusInternalServerError)
+		return
+	}
+
+	var input magic.NewGame
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		slog.WarnContext(ctx, "could not decode input", "error", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	game, err := h.svc.CreateGame(ctx, user, input)
+	if err != nil {
+		if errors.Is(err, magic.ErrInvalidGame) {
+			slog.WarnContext(ctx, "could not create game", "error", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		slog.ErrorContext(ctx, "could not create game", "error", err)
+		http.Error(w, "system error", http.StatusInternalServerError)
+		return
+	}
+
+	response := struct {
+		Game magic.Game `json:"game"`
+	}{
+		Game: game,
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *GameHandlers) GetGames(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user, ok := auth.User(ctx)
+	if !ok {
+		http.Error(w, "system error", http.StatusInternalServerError)
+		return
+	}
+
+	games, err := h.svc.GetGames(ctx, user, r.URL.Query().Get("deck_id"))
+	if err != nil {
+		slog.ErrorContext(ctx, "could not list games", "error", err)
+		http.Error(w, "system error", http.StatusInternalServerError)
+		return
+	}
+
+	response := struct {
+		Games []magic.Game `json:"games"`
+	}{
+		Games: games,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *GameHandlers) GetGame(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user, ok := auth.User(ctx)
+	if !ok {
+		http.Error(w, "system error", http.StatusInternalServerError)
+		return
+	}
+
+	game, err := h.svc.GetGame(ctx, user, r.PathValue("id"))
+	if err != nil {
+		if errors.Is(err, magic.ErrGameNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		slog.ErrorContext(ctx, "could not get game", "error", err)
+		http.Error(w, "system error", http.StatusInternalServerError)
+		return
+	}
+
+	response := struct {
+		Game magic.Game `json:"game"`
+	}{
+		Game: game,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *GameHandlers) UpdateGame(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user, ok := auth.User(ctx)
+	if !ok {
+		http.Error(w, "system error", http.StatusInternalServerError)
+		return
+	}
+
+	var input magic.NewGame
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		slog.WarnContext(ctx, "could not decode input", "error", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	game, err := h.svc.UpdateGame(ctx, user, r.PathValue("id"), input)
+	if err != nil {
+		switch {
+		case errors.Is(err, magic.ErrGameNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		case errors.Is(err, magic.ErrInvalidGame):
+			slog.WarnContext(ctx, "could not update game", "error", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		slog.ErrorContext(ctx, "could not update game", "error", err)
+		http.Error(w, "system error", http.StatusInternalServerError)
+		return
+	}
+
+	response := struct {
+		Game magic.Game `json:"game"`
+	}{
+		Game: game,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *GameHandlers) DeleteGame(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user, ok := auth.User(ctx)
+	if !ok {
+		http.Error(w, "system error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.svc.DeleteGame(ctx, user, r.PathValue("id")); err != nil {
+		if errors.Is(err, magic.ErrGameNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		slog.ErrorContext(ctx, "could not delete game", "error", err)
+		http.Error(w, "system error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *GameHandlers) GetGameStats(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user, ok := auth.User(ctx)
+	if !ok {
+		http.Error(w, "system error", http.StatusInternalServerError)
+		return
+	}
+
+	stats, err := h.svc.GetGameStats(ctx, user)
+	if err != nil {
+		slog.ErrorContext(ctx, "could not build game stats", "error", err)
+		http.Error(w, "system error", http.StatusInternalServerError)
+		return
+	}
+
+	response := struct {
+		Stats magic.GameStats `json:"stats"`
+	}{
+		Stats: stats,
+	}
+	json.NewEncoder(w).Encode(response)
+}
@@ -16,15 +16,48 @@ func App(
 	mux := http.NewServeMux()
 
 	authMW := authenticator.Middleware()
+	streamMW := authenticator.StreamMiddleware()
 
 	deckHandlers := DeckHandlers{
 		svc:     magicService,
 		userSvc: userService,
 	}
 	mux.HandleFunc("GET /api/decks", authMW(deckHandlers.GetDecks))
+	mux.HandleFunc("GET /api/decks/colors", authMW(deckHandlers.GetColorReport))
+	mux.HandleFunc("GET /api/decks/archetypes", authMW(deckHandlers.GetArchetypeStats))
+	mux.HandleFunc("GET /api/decks/ratings", authMW(deckHandlers.GetLeaderboard))
+	mux.HandleFunc("GET /api/decks/similarity", authMW(deckHandlers.GetSimilarDecks))
+	mux.HandleFunc("POST /api/decks/import", authMW(deckHandlers.ImportDeck))
+	mux.HandleFunc("GET /api/decks/{id}/stats", authMW(deckHandlers.GetDeckStats))
+	mux.HandleFunc("GET /api/decks/{id}/validate", authMW(deckHandlers.ValidateDeck))
+	mux.HandleFunc("GET /api/decks/{id}/export", authMW(deckHandlers.ExportDeck))
+	mux.HandleFunc("GET /api/decks/{id}/value", authMW(deckHandlers.GetDeckValue))
+	mux.HandleFunc("GET /api/decks/{id}/ratings", authMW(deckHandlers.GetRatingHistory))
+	mux.HandleFunc("GET /api/decks/{id}/versions", authMW(deckHandlers.GetDeckVersions))
+	mux.HandleFunc("GET /api/decks/{id}/diff", authMW(deckHandlers.DiffDeckVersions))
+	mux.HandleFunc("GET /api/decks/{id}/similarity/{other}", authMW(deckHandlers.GetDeckOverlap))
 
+	mux.HandleFunc("GET /api/accounts", authMW(deckHandlers.GetAccounts))
 	mux.HandleFunc("POST /api/accounts", authMW(deckHandlers.CreateAccount))
+	mux.HandleFunc("GET /api/accounts/{id}", authMW(deckHandlers.GetAccount))
+	mux.HandleFunc("DELETE /api/accounts/{id}", authMW(deckHandlers.DeleteAccount))
 	mux.HandleFunc("POST /api/accounts/{id}/refresh", authMW(deckHandlers.RefreshAccount))
+	mux.HandleFunc("GET /api/accounts/{id}/refresh/events", streamMW(deckHandlers.RefreshEvents))
+
+	cardHandlers := CardHandlers{
+		svc: magicService,
+	}
+	mux.HandleFunc("GET /api/cards/search", authMW(cardHandlers.SearchCards))
+
+	gameHandlers := GameHandlers{
+		svc: magicService,
+	}
+	mux.HandleFunc("GET /api/games", authMW(gameHandlers.GetGames))
+	mux.HandleFunc("POST /api/games", authMW(gameHandlers.CreateGame))
+	mux.HandleFunc("GET /api/games/stats", authMW(gameHandlers.GetGameStats))
+	mux.HandleFunc("GET /api/games/{id}", authMW(gameHandlers.GetGame))
+	mux.HandleFunc("PUT /api/games/{id}", authMW(gameHandlers.UpdateGame))
+	mux.HandleFunc("DELETE /api/games/{id}", authMW(gameHandlers.DeleteGame))
 
 	userHandlers := UserHandlers{
 		a:   authenticator,
@@ -32,6 +65,7 @@ func App(
 	}
 	mux.HandleFunc("POST /api/auth/register", userHandlers.Register)
 	mux.HandleFunc("POST /api/auth/login", userHandlers.Login)
+	mux.HandleFunc("POST /api/auth/stream-token", authMW(userHandlers.StreamToken))
 
 	return mux
 }
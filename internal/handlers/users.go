@@ -84,3 +84,29 @@ func (h *UserHandlers) Login(w http.ResponseWriter, r *http.Request) {
 
 	json.NewEncoder(w).Encode(response)
 }
+
+// StreamToken issues a short lived token for opening event streams like
+// /api/accounts/{id}/refresh/events?token=... from a browser EventSource.
+func (h *UserHandlers) StreamToken(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user, ok := auth.User(ctx)
+	if !ok {
+		http.Error(w, "system error", http.StatusInternalServerError)
+		return
+	}
+
+	token, err := h.a.GenerateStreamToken(user)
+	if err != nil {
+		slog.ErrorContext(ctx, "problem generating stream token", "error", err)
+		http.Error(w, "system error", http.StatusInternalServerError)
+		return
+	}
+
+	var response struct {
+		Token string `json:"token"`
+	}
+	response.Token = token
+
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,350 @@
+package archidekt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jcbwlkr/deck-stats/internal/domains/magic"
+	"github.com/jcbwlkr/deck-stats/internal/services"
+)
+
+// formats maps Archidekt's numeric deck formats to the format names Moxfield
+// uses so decks from both sites can be compared.
+var formats = map[int]string{
+	1:  "standard",
+	2:  "modern",
+	3:  "commander",
+	4:  "legacy",
+	5:  "vintage",
+	6:  "pauper",
+	7:  "custom",
+	8:  "frontier",
+	9:  "futurestandard",
+	10: "penny",
+	13: "brawl",
+	14: "oathbreaker",
+	15: "pioneer",
+	16: "historic",
+	17: "paupercommander",
+	18: "alchemy",
+	19: "explorer",
+	20: "historicbrawl",
+	21: "gladiator",
+	22: "premodern",
+	23: "predh",
+	24: "timeless",
+}
+
+// colors maps Archidekt's color names to our colors.
+var colors = map[string]magic.Color{
+	"White": magic.White,
+	"Blue":  magic.Blue,
+	"Black": magic.Black,
+	"Red":   magic.Red,
+	"Green": magic.Green,
+}
+
+// finishes maps Archidekt card modifiers to the finish names Moxfield uses.
+var finishes = map[string]string{
+	"Normal": "nonFoil",
+	"Foil":   "foil",
+	"Etched": "etched",
+}
+
+// Archidekt categories with special meaning.
+const (
+	categoryCommander  = "Commander"
+	categorySideboard  = "Sideboard"
+	categoryMaybeboard = "Maybeboard"
+)
+
+type Client struct {
+	url       string
+	userAgent string
+	client    *http.Client
+
+	gate chan struct{}
+}
+
+func NewClient(userAgent string, sleep time.Duration) *Client {
+
+	// This semaphore goroutine acts as a traffic cop. Every request receives
+	// from this channel to get permission to do work. This inifinite loop will
+	// push a value, wait for someone to take it, sleep a bit, then start over.
+	// This is a simple way to ensure we don't abuse the api.
+	gate := make(chan struct{})
+	go func() {
+		for {
+			gate <- struct{}{}
+			time.Sleep(sleep)
+		}
+	}()
+
+	return &Client{
+		url:       "https://archidekt.com",
+		userAgent: userAgent,
+		client:    &http.Client{},
+		gate:      gate,
+	}
+}
+
+func (c *Client) ListDecks(ctx context.Context, username string) ([]magic.Deck, error) {
+	URL := fmt.Sprintf("%s/api/decks/v3/", c.url)
+
+	decks := make([]magic.Deck, 0)
+	more := true
+
+	for page := 1; more; page++ {
+
+		q := url.Values{}
+		q.Add("ownerUsername", username)
+		q.Add("page", strconv.Itoa(page))
+
+		var data struct {
+			Next  *string        `json:"next"`
+			Decks []deckListDeck `json:"results"`
+		}
+		if err := c.get(ctx, URL+"?"+q.Encode(), &data); err != nil {
+			return nil, err
+		}
+
+		more = data.Next != nil
+
+		for _, deck := range data.Decks {
+			decks = append(decks, magic.Deck{
+				Name:      deck.Name,
+				Format:    format(deck.DeckFormat),
+				Service:   services.Archidekt,
+				ServiceID: strconv.Itoa(deck.ID),
+				URL:       fmt.Sprintf("https://archidekt.com/decks/%d", deck.ID),
+				UpdatedAt: deck.UpdatedAt,
+			})
+		}
+	}
+
+	return decks, nil
+}
+
+func (c *Client) AddDeckDetails(ctx context.Context, d *magic.Deck) error {
+
+	url := fmt.Sprintf("%s/api/decks/%s/", c.url, d.ServiceID)
+
+	var data deck
+	if err := c.get(ctx, url, &data); err != nil {
+		return err
+	}
+
+	// Archidekt has no hubs.
+	d.Archetypes = []magic.Archetype{}
+
+	// Categories like Sideboard and Maybeboard aren't part of the deck.
+	outside := map[string]bool{}
+	for _, cat := range data.Categories {
+		outside[cat.Name] = !cat.IncludedInDeck
+	}
+
+	slices.SortFunc(data.Cards, func(a, b card) int {
+		return strings.Compare(a.Card.UID, b.Card.UID)
+	})
+
+	d.Cards = []magic.DeckCard{}
+	for _, card := range data.Cards {
+		d.Cards = append(d.Cards, card.DeckCard(card.board(outside)))
+	}
+
+	// Archidekt doesn't report leaders or a color identity so they come from
+	// the cards.
+	d.ResolveLeaders()
+
+	return nil
+}
+
+// get waits for permission from the gate then decodes the JSON response from
+// URL into v.
+func (c *Client) get(ctx context.Context, URL string, v any) error {
+
+	// Block until we have permission to call or our context is canceled.
+	select {
+	case <-c.gate:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, URL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Add("Accept", "application/json")
+	req.Header.Add("User-Agent", c.userAgent)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("archidekt: api status %s", resp.Status)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func format(f int) string {
+	if name, ok := formats[f]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+type deckListDeck struct {
+	ID             int       `json:"id"`
+	Name           string    `json:"name"`
+	Size           int       `json:"size"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+	CreatedAt      time.Time `json:"createdAt"`
+	DeckFormat     int       `json:"deckFormat"`
+	ViewCount      int       `json:"viewCount"`
+	Private        bool      `json:"private"`
+	Unlisted       bool      `json:"unlisted"`
+	Theorycrafted  bool      `json:"theorycrafted"`
+	HasDescription bool      `json:"hasDescription"`
+	Tags           []any     `json:"tags"`
+	ParentFolderID int       `json:"parentFolderId"`
+	Owner          struct {
+		ID       int    `json:"id"`
+		Username string `json:"username"`
+	} `json:"owner"`
+}
+
+type card struct {
+	ID         int      `json:"id"`
+	Categories []string `json:"categories"`
+	Companion  bool     `json:"companion"`
+	Modifier   string   `json:"modifier"`
+	Quantity   int      `json:"quantity"`
+	Card       struct {
+		ID         int    `json:"id"`
+		UID        string `json:"uid"` // Scryfall ID
+		Artist     string `json:"artist"`
+		ReleasedAt string `json:"releasedAt"`
+		Edition    struct {
+			EditionCode string `json:"editioncode"`
+			EditionName string `json:"editionname"`
+		} `json:"edition"`
+		OracleCard struct {
+			ID            int               `json:"id"`
+			Cmc           float64           `json:"cmc"`
+			ColorIdentity []string          `json:"colorIdentity"`
+			Colors        []string          `json:"colors"`
+			Layout        string            `json:"layout"`
+			Legalities    map[string]string `json:"legalities"`
+			ManaCost      string            `json:"manaCost"`
+			Name          string            `json:"name"`
+			SubTypes      []string          `json:"subTypes"`
+			SuperTypes    []string          `json:"superTypes"`
+			Text          string            `json:"text"`
+			Types         []string          `json:"types"`
+		} `json:"oracleCard"`
+		Rarity          string `json:"rarity"`
+		CollectorNumber string `json:"collectorNumber"`
+	} `json:"card"`
+}
+
+// board decides which board the card is on from its first category. Oathbreaker
+// decks put the signature spell in the Commander category alongside the
+// planeswalker which Deck.ResolveLeaders sorts out.
+func (c card) board(outside map[string]bool) string {
+	if c.Companion {
+		return magic.BoardCompanions
+	}
+
+	var category string
+	if len(c.Categories) > 0 {
+		category = c.Categories[0]
+	}
+
+	switch {
+	case category == categoryCommander:
+		return magic.BoardCommanders
+	case category == categorySideboard:
+		return magic.BoardSideboard
+	case category == categoryMaybeboard, outside[category]:
+		return magic.BoardMaybeboard
+	}
+	return magic.BoardMainboard
+}
+
+// TypeLine builds a type line like "Legendary Creature — Cat Avatar".
+func (c card) TypeLine() string {
+	oc := c.Card.OracleCard
+	line := strings.Join(append(slices.Clone(oc.SuperTypes), oc.Types...), " ")
+	if len(oc.SubTypes) > 0 {
+		line += " — " + strings.Join(oc.SubTypes, " ")
+	}
+	return line
+}
+
+// DeckCard converts the card to the domain representation for a given board.
+func (c card) DeckCard(board string) magic.DeckCard {
+	finish, ok := finishes[c.Modifier]
+	if !ok {
+		finish = finishes["Normal"]
+	}
+
+	return magic.DeckCard{
+		Board:           board,
+		Quantity:        c.Quantity,
+		Finish:          finish,
+		ScryfallID:      c.Card.UID,
+		Name:            c.Card.OracleCard.Name,
+		Set:             c.Card.Edition.EditionCode,
+		CollectorNumber: c.Card.CollectorNumber,
+		Rarity:          strings.ToLower(c.Card.Rarity),
+		ManaCost:        c.Card.OracleCard.ManaCost,
+		CMC:             c.Card.OracleCard.Cmc,
+		TypeLine:        c.TypeLine(),
+		OracleText:      c.Card.OracleCard.Text,
+		Colors:          toColors(c.Card.OracleCard.Colors),
+		ColorIdentity:   toColors(c.Card.OracleCard.ColorIdentity),
+		Legalities:      c.Card.OracleCard.Legalities,
+	}
+}
+
+// toColors converts Archidekt color names to our colors in WUBRG order.
+func toColors(names []string) magic.ColorIdentity {
+	id := make(magic.ColorIdentity, 0, len(names))
+	for _, name := range names {
+		if c, ok := colors[name]; ok {
+			id = append(id, c)
+		}
+	}
+	return id.Normalize()
+}
+
+type deck struct {
+	ID          int       `json:"id"`
+	Name        string    `json:"name"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+	DeckFormat  int       `json:"deckFormat"`
+	Description string    `json:"description"`
+	ViewCount   int       `json:"viewCount"`
+	Private     bool      `json:"private"`
+	Unlisted    bool      `json:"unlisted"`
+	Categories  []struct {
+		ID              int    `json:"id"`
+		Name            string `json:"name"`
+		IsPremier       bool   `json:"isPremier"`
+		IncludedInDeck  bool   `json:"includedInDeck"`
+		IncludedInPrice bool   `json:"includedInPrice"`
+	} `json:"categories"`
+	Cards []card `json:"cards"`
+}
@@ -0,0 +1,249 @@
+package archidekt
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/jcbwlkr/deck-stats/internal/domains/magic"
+	"github.com/jcbwlkr/deck-stats/internal/services"
+)
+
+func TestClientListDecks(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		filename := ""
+		switch r.URL.Path {
+		case "/api/decks/v3/":
+			if r.URL.Query().Get("ownerUsername") != "jcbwlkr" {
+				break
+			}
+			if r.URL.Query().Get("page") == "1" {
+				filename = "testdata/decks-page-01.json"
+			} else {
+				filename = "testdata/decks-page-02.json"
+			}
+		case "/api/decks/5011201/":
+			filename = "testdata/deck-5011201.json"
+		case "/api/decks/5011202/":
+			filename = "testdata/deck-5011202.json"
+		case "/api/decks/3180443/":
+			filename = "testdata/deck-3180443.json"
+		}
+
+		if filename == "" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		f, err := os.Open(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(w, f)
+	}))
+	defer srv.Close()
+
+	client := NewClient("", 0)
+	client.url = srv.URL
+
+	ctx := context.Background()
+
+	deckList, err := client.ListDecks(ctx, "jcbwlkr")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := len(deckList), 3; got != want {
+		t.Fatalf("should have %d decks but got %d", want, got)
+	}
+
+	for i := range deckList {
+		err := client.AddDeckDetails(ctx, &deckList[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Card lists are checked separately below.
+	ignoreCards := cmpopts.IgnoreFields(magic.Deck{}, "Cards")
+
+	poison := magic.Deck{
+		Name:          "Drink your Poison",
+		Format:        "oathbreaker",
+		Service:       services.Archidekt,
+		ServiceID:     "5011201",
+		URL:           "https://archidekt.com/decks/5011201",
+		ColorIdentity: magic.Simic,
+		Leaders: magic.Leaders{
+			Oathbreakers: []magic.Card{
+				{
+					ID:   "222a736e-d819-452d-aeda-eb848c4b2302",
+					Name: "Tamiyo, Compleated Sage",
+				},
+			},
+			SignatureSpells: []magic.Card{
+				{
+					ID:   "ac625f30-ed91-4b21-ada8-aaa5b2ad79b8",
+					Name: "Prologue to Phyresis",
+				},
+			},
+		},
+		Archetypes: []magic.Archetype{},
+		UpdatedAt:  parseUTC("2024-11-30T21:42:01.61Z"),
+	}
+	if diff := cmp.Diff(poison, deckList[0], ignoreCards); diff != "" {
+		t.Errorf("first response should be poison but was:\n%s", diff)
+	}
+
+	vialSmasher := magic.Deck{
+		Name:          "1,000 Smashed Vials",
+		Format:        "commander",
+		Service:       services.Archidekt,
+		ServiceID:     "5011202",
+		URL:           "https://archidekt.com/decks/5011202",
+		ColorIdentity: magic.Grixis,
+		Leaders: magic.Leaders{
+			Commanders: []magic.Card{
+				{
+					ID:   "4e439cd0-5ba1-45af-a868-f408e0a50465",
+					Name: "Vial Smasher the Fierce",
+				},
+				{
+					ID:   "714c3a1f-7b30-4ed8-8f38-6176758741fb",
+					Name: "Sakashima of a Thousand Faces",
+				},
+			},
+		},
+		Archetypes: []magic.Archetype{},
+		UpdatedAt:  parseUTC("2024-11-30T21:41:45.337Z"),
+	}
+	if diff := cmp.Diff(vialSmasher, deckList[1], ignoreCards); diff != "" {
+		t.Errorf("second response should be vial smasher but was:\n%s", diff)
+	}
+
+	cats := magic.Deck{
+		Name:          "Cats!",
+		Format:        "commander",
+		Service:       services.Archidekt,
+		ServiceID:     "3180443",
+		URL:           "https://archidekt.com/decks/3180443",
+		ColorIdentity: magic.Selesnya,
+		Leaders: magic.Leaders{
+			Commanders: []magic.Card{
+				{
+					ID:   "81dc3d00-97cd-4549-b5a4-15a1e08767f5",
+					Name: "Arahbo, Roar of the World",
+				},
+			},
+			Companion: &magic.Card{
+				ID:   "d4ebed0b-8060-4a7b-a060-5cfcd2172b16",
+				Name: "Kaheera, the Orphanguard",
+			},
+		},
+		Archetypes: []magic.Archetype{},
+		UpdatedAt:  parseUTC("2023-03-31T17:33:58.21Z"),
+	}
+	if diff := cmp.Diff(cats, deckList[2], ignoreCards); diff != "" {
+		t.Errorf("last response should be cats but was:\n%s", diff)
+	}
+
+	// Check the full card list of the cats deck.
+	quantities := map[string]int{}
+	for _, c := range deckList[2].Cards {
+		quantities[c.Board] += c.Quantity
+	}
+	wantQuantities := map[string]int{
+		magic.BoardCommanders: 1,
+		magic.BoardCompanions: 1,
+		magic.BoardMainboard:  7,
+		magic.BoardSideboard:  1,
+		magic.BoardMaybeboard: 2,
+	}
+	if diff := cmp.Diff(wantQuantities, quantities); diff != "" {
+		t.Errorf("cats deck has wrong card quantities per board:\n%s", diff)
+	}
+
+	ajani := magic.DeckCard{
+		Board:           magic.BoardMainboard,
+		Quantity:        1,
+		Finish:          "foil",
+		ScryfallID:      "791fdd9a-0ab6-4db9-84f9-859d2d862518",
+		Name:            "Ajani, Valiant Protector",
+		Set:             "aer",
+		CollectorNumber: "185",
+		Rarity:          "mythic",
+		ManaCost:        "{4}{G}{W}",
+		CMC:             6,
+		TypeLine:        "Legendary Planeswalker — Ajani",
+		Colors:          magic.Selesnya,
+		ColorIdentity:   magic.Selesnya,
+		Legalities: magic.Legalities{
+			"standard":        "not_legal",
+			"future":          "not_legal",
+			"historic":        "not_legal",
+			"timeless":        "not_legal",
+			"gladiator":       "not_legal",
+			"pioneer":         "legal",
+			"explorer":        "not_legal",
+			"modern":          "legal",
+			"legacy":          "legal",
+			"pauper":          "not_legal",
+			"vintage":         "legal",
+			"penny":           "not_legal",
+			"commander":       "legal",
+			"oathbreaker":     "legal",
+			"standardbrawl":   "not_legal",
+			"brawl":           "not_legal",
+			"alchemy":         "not_legal",
+			"paupercommander": "not_legal",
+			"duel":            "legal",
+			"oldschool":       "not_legal",
+			"premodern":       "not_legal",
+			"predh":           "not_legal",
+		},
+	}
+	var found bool
+	for _, got := range deckList[2].Cards {
+		if got.Name != ajani.Name {
+			continue
+		}
+		found = true
+		got.OracleText = "" // Too long to be worth comparing
+		if diff := cmp.Diff(ajani, got); diff != "" {
+			t.Errorf("cats deck has wrong data for %s:\n%s", ajani.Name, diff)
+		}
+	}
+	if !found {
+		t.Errorf("cats deck should include %s", ajani.Name)
+	}
+}
+
+func TestClientAPIError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	client := NewClient("", 0)
+	client.url = srv.URL
+
+	if _, err := client.ListDecks(context.Background(), "jcbwlkr"); err == nil {
+		t.Error("listing decks should fail when the api returns an error status")
+	}
+}
+
+func parseUTC(s string) time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		panic(err)
+	}
+
+	return t
+}
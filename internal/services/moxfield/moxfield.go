@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -85,16 +86,13 @@ func (c *Client) ListDecks(ctx context.Context, username string) ([]magic.Deck,
 
 		for _, deck := range data.Decks {
 			d := magic.Deck{
-				Name:      deck.Name,
-				Format:    deck.Format,
-				Service:   services.Moxfield,
-				ServiceID: deck.PublicID,
-				URL:       deck.PublicURL,
-				UpdatedAt: deck.LastUpdatedAtUtc,
-			}
-
-			for _, c := range deck.ColorIdentity {
-				d.ColorIdentity = append(d.ColorIdentity, magic.Color(strings.ToLower(c)))
+				Name:          deck.Name,
+				Format:        deck.Format,
+				Service:       services.Moxfield,
+				ServiceID:     deck.PublicID,
+				URL:           deck.PublicURL,
+				UpdatedAt:     deck.LastUpdatedAtUtc,
+				ColorIdentity: colors(deck.ColorIdentity),
 			}
 
 			decks = append(decks, d)
@@ -110,6 +108,7 @@ func (c *Client) AddDeckDetails(ctx context.Context, d *magic.Deck) error {
 	select {
 	case <-c.gate:
 	case <-ctx.Done():
+		return ctx.Err()
 	}
 
 	url := fmt.Sprintf("%s/v3/decks/all/%s", c.url, d.ServiceID)
@@ -136,6 +135,8 @@ func (c *Client) AddDeckDetails(ctx context.Context, d *magic.Deck) error {
 		return err
 	}
 
+	d.Version = data.Version
+
 	d.Archetypes = []magic.Archetype{}
 	for _, hub := range data.Hubs {
 		d.Archetypes = append(d.Archetypes, magic.Archetype{
@@ -145,20 +146,20 @@ func (c *Client) AddDeckDetails(ctx context.Context, d *magic.Deck) error {
 	}
 
 	if data.Format == "oathbreaker" {
-		for _, card := range data.Boards.Commanders.Cards {
+		for _, card := range data.Boards.Commanders.SortedCards() {
 			d.Leaders.Oathbreakers = append(d.Leaders.Oathbreakers, magic.Card{
 				ID:   card.Card.ScryfallID,
 				Name: card.PreferredName(),
 			})
 		}
-		for _, card := range data.Boards.SignatureSpells.Cards {
+		for _, card := range data.Boards.SignatureSpells.SortedCards() {
 			d.Leaders.SignatureSpells = append(d.Leaders.SignatureSpells, magic.Card{
 				ID:   card.Card.ScryfallID,
 				Name: card.PreferredName(),
 			})
 		}
 	} else {
-		for _, card := range data.Boards.Commanders.Cards {
+		for _, card := range data.Boards.Commanders.SortedCards() {
 			d.Leaders.Commanders = append(d.Leaders.Commanders, magic.Card{
 				ID:   card.Card.ScryfallID,
 				Name: card.PreferredName(),
@@ -166,13 +167,32 @@ func (c *Client) AddDeckDetails(ctx context.Context, d *magic.Deck) error {
 		}
 	}
 
-	for _, card := range data.Boards.Companions.Cards {
+	for _, card := range data.Boards.Companions.SortedCards() {
 		d.Leaders.Companion = &magic.Card{
 			ID:   card.Card.ScryfallID,
 			Name: card.Card.Name,
 		}
 	}
 
+	boards := []struct {
+		name  string
+		board board
+	}{
+		{magic.BoardCommanders, data.Boards.Commanders},
+		{magic.BoardCompanions, data.Boards.Companions},
+		{magic.BoardSignatureSpells, data.Boards.SignatureSpells},
+		{magic.BoardMainboard, data.Boards.Mainboard},
+		{magic.BoardSideboard, data.Boards.Sideboard},
+		{magic.BoardMaybeboard, data.Boards.Maybeboard},
+	}
+
+	d.Cards = []magic.DeckCard{}
+	for _, b := range boards {
+		for _, card := range b.board.SortedCards() {
+			d.Cards = append(d.Cards, card.DeckCard(b.name))
+		}
+	}
+
 	return nil
 }
 
@@ -252,9 +272,30 @@ type deckListDeck struct {
 	IsMatureAuto bool `json:"isMatureAuto,omitempty"`
 }
 
+type board struct {
+	Count int             `json:"count"`
+	Cards map[string]card `json:"cards"`
+}
+
+// SortedCards returns the cards on the board in a stable order. The API gives
+// us a map so without this the order would change every time.
+func (b board) SortedCards() []card {
+	cards := make([]card, 0, len(b.Cards))
+	for _, c := range b.Cards {
+		cards = append(cards, c)
+	}
+	slices.SortFunc(cards, func(a, b card) int {
+		return strings.Compare(a.Card.ScryfallID, b.Card.ScryfallID)
+	})
+	return cards
+}
+
 type card struct {
-	Quantity int `json:"quantity"`
-	Card     struct {
+	Quantity  int    `json:"quantity"`
+	BoardType string `json:"boardType"`
+	Finish    string `json:"finish"`
+	IsProxy   bool   `json:"isProxy"`
+	Card      struct {
 		ID             string   `json:"id"`
 		UniqueCardID   string   `json:"uniqueCardId"`
 		ScryfallID     string   `json:"scryfall_id"`
@@ -272,6 +313,15 @@ type card struct {
 		Colors         []string `json:"colors"`
 		ColorIndicator []string `json:"color_indicator"`
 		ColorIdentity  []string `json:"color_identity"`
+		Rarity         string   `json:"rarity"`
+
+		// Legalities has the card's status like "legal" or "banned" in
+		// every format keyed by format.
+		Legalities map[string]string `json:"legalities"`
+
+		// Prices has keys like "usd", "usd_foil" or "ck_etched" along with
+		// buylist prices and a timestamp we don't use.
+		Prices map[string]any `json:"prices"`
 	} `json:"card"`
 }
 
@@ -282,6 +332,75 @@ func (c card) PreferredName() string {
 	return c.Card.Name
 }
 
+// DeckCard converts the card to the domain representation for a given board.
+func (c card) DeckCard(board string) magic.DeckCard {
+	return magic.DeckCard{
+		Board:           board,
+		Quantity:        c.Quantity,
+		Finish:          c.Finish,
+		IsProxy:         c.IsProxy,
+		ScryfallID:      c.Card.ScryfallID,
+		Name:            c.Card.Name,
+		Set:             c.Card.Set,
+		CollectorNumber: c.Card.Cn,
+		Rarity:          c.Card.Rarity,
+		ManaCost:        c.Card.ManaCost,
+		CMC:             c.Card.Cmc,
+		TypeLine:        c.Card.TypeLine,
+		OracleText:      c.Card.OracleText,
+		Colors:          colors(c.Card.Colors),
+		ColorIdentity:   colors(c.Card.ColorIdentity),
+		Legalities:      c.Card.Legalities,
+		Prices:          c.prices(),
+	}
+}
+
+// prices picks the retail prices out of the card's prices. Keys are a vendor
+// with an optional finish so "usd" is the non-foil price and "usd_foil" is the
+// foil price.
+func (c card) prices() []magic.Price {
+	var prices []magic.Price
+	for key, v := range c.Card.Prices {
+		amount, ok := v.(float64)
+		if !ok || amount <= 0 {
+			continue
+		}
+
+		vendor, finish, _ := strings.Cut(key, "_")
+		if !slices.Contains(magic.Vendors, vendor) {
+			continue
+		}
+		switch finish {
+		case "":
+			finish = "nonFoil"
+		case "foil", "etched":
+		default:
+			continue // Buylist prices and quantities
+		}
+
+		prices = append(prices, magic.Price{
+			Vendor: vendor,
+			Finish: finish,
+			Amount: amount,
+		})
+	}
+
+	slices.SortFunc(prices, func(a, b magic.Price) int {
+		return strings.Compare(a.Vendor+a.Finish, b.Vendor+b.Finish)
+	})
+	return prices
+}
+
+// colors converts the upper case color letters from the api to our colors in
+// WUBRG order.
+func colors(s []string) magic.ColorIdentity {
+	id := make(magic.ColorIdentity, 0, len(s))
+	for _, c := range s {
+		id = append(id, magic.Color(strings.ToLower(c)))
+	}
+	return id.Normalize()
+}
+
 type deck struct {
 	ID          string `json:"id"`
 	Name        string `json:"name"`
@@ -291,18 +410,12 @@ type deck struct {
 	PublicURL   string `json:"publicUrl"`
 	PublicID    string `json:"publicId"`
 	Boards      struct {
-		Commanders struct {
-			Count int             `json:"count"`
-			Cards map[string]card `json:"cards"`
-		} `json:"commanders"`
-		Companions struct {
-			Count int             `json:"count"`
-			Cards map[string]card `json:"cards"`
-		} `json:"companions"`
-		SignatureSpells struct {
-			Count int             `json:"count"`
-			Cards map[string]card `json:"cards"`
-		} `json:"signatureSpells"`
+		Mainboard       board `json:"mainboard"`
+		Sideboard       board `json:"sideboard"`
+		Maybeboard      board `json:"maybeboard"`
+		Commanders      board `json:"commanders"`
+		Companions      board `json:"companions"`
+		SignatureSpells board `json:"signatureSpells"`
 	} `json:"boards"`
 	Version int `json:"version"`
 	Hubs    []struct {
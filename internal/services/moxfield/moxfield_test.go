@@ -6,10 +6,12 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"slices"
 	"testing"
 	"time"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 
 	"github.com/jcbwlkr/deck-stats/internal/domains/magic"
 	"github.com/jcbwlkr/deck-stats/internal/services"
@@ -71,6 +73,9 @@ func TestClientListDecks(t *testing.T) {
 		}
 	}
 
+	// Card lists are checked separately below.
+	ignoreCards := cmpopts.IgnoreFields(magic.Deck{}, "Cards")
+
 	vialSmasher := magic.Deck{
 		Name:          "1,000 Smashed Vials",
 		Format:        "commander",
@@ -94,9 +99,10 @@ func TestClientListDecks(t *testing.T) {
 			{Name: "Burn", Description: "A deck that focuses on dealing direct damage to an opponent."},
 			{Name: "Clones", Description: "A deck focusing on clone effects, or the ability to copy another creature."},
 		},
+		Version:   1,
 		UpdatedAt: parseUTC("2024-11-30T21:41:45.337Z"),
 	}
-	if diff := cmp.Diff(vialSmasher, deckList[1]); diff != "" {
+	if diff := cmp.Diff(vialSmasher, deckList[1], ignoreCards); diff != "" {
 		t.Errorf("second response should be vial smasher but was:\n%s", diff)
 	}
 
@@ -122,7 +128,7 @@ func TestClientListDecks(t *testing.T) {
 		Archetypes: []magic.Archetype{},
 		UpdatedAt:  parseUTC("2023-03-31T17:33:58.21Z"),
 	}
-	if diff := cmp.Diff(cats, deckList[22]); diff != "" {
+	if diff := cmp.Diff(cats, deckList[22], ignoreCards); diff != "" {
 		t.Errorf("second to last response should be Arahbo but was:\n%s", diff)
 	}
 
@@ -150,7 +156,7 @@ func TestClientListDecks(t *testing.T) {
 		Archetypes: []magic.Archetype{},
 		UpdatedAt:  parseUTC("2024-11-30T21:42:01.61Z"),
 	}
-	if diff := cmp.Diff(poison, deckList[0]); diff != "" {
+	if diff := cmp.Diff(poison, deckList[0], ignoreCards); diff != "" {
 		t.Errorf("first response should be poison but was:\n%s", diff)
 	}
 
@@ -172,7 +178,7 @@ func TestClientListDecks(t *testing.T) {
 		Archetypes: []magic.Archetype{},
 		UpdatedAt:  parseUTC("2024-11-30T21:41:34.123Z"),
 	}
-	if diff := cmp.Diff(blanka, deckList[2]); diff != "" {
+	if diff := cmp.Diff(blanka, deckList[2], ignoreCards); diff != "" {
 		t.Errorf("third response should be blanka but was:\n%s", diff)
 	}
 
@@ -194,9 +200,97 @@ func TestClientListDecks(t *testing.T) {
 		Archetypes: []magic.Archetype{},
 		UpdatedAt:  parseUTC("2024-10-20T13:45:59.85Z"),
 	}
-	if diff := cmp.Diff(winota, deckList[9]); diff != "" {
+	if diff := cmp.Diff(winota, deckList[9], ignoreCards); diff != "" {
 		t.Errorf("tenth response should be winota but was:\n%s", diff)
 	}
+
+	// Check the full card list of the cats deck.
+	entries := map[string]int{}
+	quantities := map[string]int{}
+	for _, c := range deckList[22].Cards {
+		entries[c.Board]++
+		quantities[c.Board] += c.Quantity
+	}
+	wantEntries := map[string]int{
+		magic.BoardCommanders: 1,
+		magic.BoardCompanions: 1,
+		magic.BoardMainboard:  82,
+		magic.BoardSideboard:  1,
+		magic.BoardMaybeboard: 66,
+	}
+	if diff := cmp.Diff(wantEntries, entries); diff != "" {
+		t.Errorf("cats deck has wrong card entries per board:\n%s", diff)
+	}
+	wantQuantities := map[string]int{
+		magic.BoardCommanders: 1,
+		magic.BoardCompanions: 1,
+		magic.BoardMainboard:  99,
+		magic.BoardSideboard:  1,
+		magic.BoardMaybeboard: 67,
+	}
+	if diff := cmp.Diff(wantQuantities, quantities); diff != "" {
+		t.Errorf("cats deck has wrong card quantities per board:\n%s", diff)
+	}
+
+	ajani := magic.DeckCard{
+		Board:           magic.BoardMainboard,
+		Quantity:        1,
+		Finish:          "foil",
+		ScryfallID:      "791fdd9a-0ab6-4db9-84f9-859d2d862518",
+		Name:            "Ajani, Valiant Protector",
+		Set:             "aer",
+		CollectorNumber: "185",
+		Rarity:          "mythic",
+		ManaCost:        "{4}{G}{W}",
+		CMC:             6,
+		TypeLine:        "Legendary Planeswalker — Ajani",
+		Colors:          magic.Selesnya,
+		ColorIdentity:   magic.Selesnya,
+		Legalities: magic.Legalities{
+			"standard":        "not_legal",
+			"future":          "not_legal",
+			"historic":        "not_legal",
+			"timeless":        "not_legal",
+			"gladiator":       "not_legal",
+			"pioneer":         "legal",
+			"explorer":        "not_legal",
+			"modern":          "legal",
+			"legacy":          "legal",
+			"pauper":          "not_legal",
+			"vintage":         "legal",
+			"penny":           "not_legal",
+			"commander":       "legal",
+			"oathbreaker":     "legal",
+			"standardbrawl":   "not_legal",
+			"brawl":           "not_legal",
+			"alchemy":         "not_legal",
+			"paupercommander": "not_legal",
+			"duel":            "legal",
+			"oldschool":       "not_legal",
+			"premodern":       "not_legal",
+			"predh":           "not_legal",
+		},
+		Prices: []magic.Price{
+			{Vendor: "ck", Finish: "foil", Amount: 2.49},
+			{Vendor: "csi", Finish: "foil", Amount: 2.49},
+			{Vendor: "ct", Finish: "foil", Amount: 1.8},
+			{Vendor: "eur", Finish: "foil", Amount: 1.38},
+			{Vendor: "scg", Finish: "foil", Amount: 2.49},
+			{Vendor: "tix", Finish: "nonFoil", Amount: 0.03},
+			{Vendor: "usd", Finish: "foil", Amount: 2.02},
+		},
+	}
+	i := slices.IndexFunc(deckList[22].Cards, func(c magic.DeckCard) bool {
+		return c.Name == ajani.Name
+	})
+	if i < 0 {
+		t.Fatalf("cats deck should include %s", ajani.Name)
+	}
+	got := deckList[22].Cards[i]
+	got.OracleText = "" // Too long to be worth comparing
+	if diff := cmp.Diff(ajani, got); diff != "" {
+		t.Errorf("cats deck has wrong data for %s:\n%s", ajani.Name, diff)
+	}
 }
 
 func parseUTC(s string) time.Time {
@@ -1,3 +1,10 @@
 package services
 
-const Moxfield = "moxfield"
+const (
+	Moxfield  = "moxfield"
+	Archidekt = "archidekt"
+
+	// Manual decks are imported from plain text rather than synced from a
+	// site.
+	Manual = "manual"
+)
@@ -0,0 +1,238 @@
+package scryfall
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jcbwlkr/deck-stats/internal/domains/magic"
+)
+
+// batchSize is the most identifiers the collection endpoint accepts at once.
+const batchSize = 75
+
+type Client struct {
+	url       string
+	userAgent string
+	client    *http.Client
+
+	gate chan struct{}
+}
+
+func NewClient(userAgent string, sleep time.Duration) *Client {
+
+	// This semaphore goroutine acts as a traffic cop. Every request receives
+	// from this channel to get permission to do work. This inifinite loop will
+	// push a value, wait for someone to take it, sleep a bit, then start over.
+	// This is a simple way to ensure we don't abuse the api.
+	gate := make(chan struct{})
+	go func() {
+		for {
+			gate <- struct{}{}
+			time.Sleep(sleep)
+		}
+	}()
+
+	return &Client{
+		url:       "https://api.scryfall.com",
+		userAgent: userAgent,
+		client:    &http.Client{},
+		gate:      gate,
+	}
+}
+
+// LookupCards fills in the card data for cards that are only known by name or
+// by set and collector number. Cards that can't be found are reported in a
+// magic.CardsNotFoundError after every other card has been filled in.
+func (c *Client) LookupCards(ctx context.Context, cards []magic.DeckCard) error {
+	var missing []string
+
+	for start := 0; start < len(cards); start += batchSize {
+		end := min(start+batchSize, len(cards))
+		batch := cards[start:end]
+
+		found, err := c.collection(ctx, batch)
+		if err != nil {
+			return err
+		}
+
+		for i := range batch {
+			sc, ok := match(batch[i], found)
+			if !ok {
+				missing = append(missing, batch[i].Name)
+				continue
+			}
+			sc.fill(&batch[i])
+		}
+	}
+
+	if len(missing) > 0 {
+		return magic.CardsNotFoundError{Names: missing}
+	}
+	return nil
+}
+
+type identifier struct {
+	Name            string `json:"name,omitempty"`
+	Set             string `json:"set,omitempty"`
+	CollectorNumber string `json:"collector_number,omitempty"`
+}
+
+func (c *Client) collection(ctx context.Context, cards []magic.DeckCard) ([]card, error) {
+
+	// Block until we have permission to call or our context is canceled.
+	select {
+	case <-c.gate:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	var body struct {
+		Identifiers []identifier `json:"identifiers"`
+	}
+	for _, card := range cards {
+		if card.Set != "" && card.CollectorNumber != "" {
+			body.Identifiers = append(body.Identifiers, identifier{
+				Set:             card.Set,
+				CollectorNumber: card.CollectorNumber,
+			})
+		} else {
+			body.Identifiers = append(body.Identifiers, identifier{Name: card.Name})
+		}
+	}
+
+	b, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	url := fmt.Sprintf("%s/cards/collection", c.url)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("Accept", "application/json")
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("User-Agent", c.userAgent)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("scryfall: api status %s", resp.Status)
+	}
+
+	var data struct {
+		Data []card `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return nil, err
+	}
+
+	return data.Data, nil
+}
+
+// match finds the result for a requested card. Printings are matched by set
+// and collector number, everything else by the full name or the name of the
+// front face.
+func match(dc magic.DeckCard, found []card) (card, bool) {
+	for _, sc := range found {
+		if dc.Set != "" && dc.CollectorNumber != "" {
+			if strings.EqualFold(sc.Set, dc.Set) && strings.EqualFold(sc.CollectorNumber, dc.CollectorNumber) {
+				return sc, true
+			}
+			continue
+		}
+
+		front, _, _ := strings.Cut(sc.Name, " // ")
+		if strings.EqualFold(sc.Name, dc.Name) || strings.EqualFold(front, dc.Name) {
+			return sc, true
+		}
+	}
+	return card{}, false
+}
+
+type face struct {
+	Name       string   `json:"name"`
+	ManaCost   string   `json:"mana_cost"`
+	TypeLine   string   `json:"type_line"`
+	OracleText string   `json:"oracle_text"`
+	Colors     []string `json:"colors"`
+}
+
+type card struct {
+	ID              string            `json:"id"`
+	OracleID        string            `json:"oracle_id"`
+	Name            string            `json:"name"`
+	Layout          string            `json:"layout"`
+	Set             string            `json:"set"`
+	SetName         string            `json:"set_name"`
+	CollectorNumber string            `json:"collector_number"`
+	Rarity          string            `json:"rarity"`
+	ManaCost        string            `json:"mana_cost"`
+	Cmc             float64           `json:"cmc"`
+	TypeLine        string            `json:"type_line"`
+	OracleText      string            `json:"oracle_text"`
+	Colors          []string          `json:"colors"`
+	ColorIdentity   []string          `json:"color_identity"`
+	Legalities      map[string]string `json:"legalities"`
+	CardFaces       []face            `json:"card_faces"`
+}
+
+// fill copies the card data onto dc. Double faced cards keep some of their
+// data on each face so it is joined together the way Moxfield reports it.
+func (c card) fill(dc *magic.DeckCard) {
+	dc.ScryfallID = c.ID
+	dc.Name = c.Name
+	dc.Set = c.Set
+	dc.CollectorNumber = c.CollectorNumber
+	dc.Rarity = c.Rarity
+	dc.ManaCost = c.ManaCost
+	dc.CMC = c.Cmc
+	dc.TypeLine = c.TypeLine
+	dc.OracleText = c.OracleText
+	dc.Colors = colors(c.Colors)
+	dc.ColorIdentity = colors(c.ColorIdentity)
+	dc.Legalities = c.Legalities
+
+	if len(c.CardFaces) == 0 {
+		return
+	}
+
+	var costs, texts []string
+	var faceColors []string
+	for _, f := range c.CardFaces {
+		if f.ManaCost != "" {
+			costs = append(costs, f.ManaCost)
+		}
+		texts = append(texts, f.OracleText)
+		faceColors = append(faceColors, f.Colors...)
+	}
+	if dc.ManaCost == "" {
+		dc.ManaCost = strings.Join(costs, " // ")
+	}
+	if dc.OracleText == "" {
+		dc.OracleText = strings.Join(texts, "\n\n")
+	}
+	if c.Colors == nil {
+		dc.Colors = colors(faceColors)
+	}
+}
+
+// colors converts the upper case color letters from the api to our colors in
+// WUBRG order.
+func colors(s []string) magic.ColorIdentity {
+	id := make(magic.ColorIdentity, 0, len(s))
+	for _, c := range s {
+		id = append(id, magic.Color(strings.ToLower(c)))
+	}
+	return id.Normalize()
+}
@@ -0,0 +1,123 @@
+package scryfall
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/jcbwlkr/deck-stats/internal/domains/magic"
+)
+
+func TestClientLookupCards(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/cards/collection" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		var body struct {
+			Identifiers []identifier `json:"identifiers"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		want := []identifier{
+			{Set: "cmr", CollectorNumber: "491"},
+			{Name: "Delver of Secrets"},
+			{Name: "Not A Real Card"},
+		}
+		if diff := cmp.Diff(want, body.Identifiers); diff != "" {
+			t.Errorf("wrong identifiers requested:\n%s", diff)
+		}
+
+		f, err := os.Open("testdata/collection.json")
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(w, f)
+	}))
+	defer srv.Close()
+
+	client := NewClient("", 0)
+	client.url = srv.URL
+
+	cards := []magic.DeckCard{
+		{Board: magic.BoardCommanders, Quantity: 1, Name: "Vial Smasher the Fierce", Set: "cmr", CollectorNumber: "491", Finish: "nonFoil"},
+		{Board: magic.BoardMainboard, Quantity: 4, Name: "Delver of Secrets", Finish: "foil"},
+		{Board: magic.BoardMainboard, Quantity: 1, Name: "Not A Real Card", Finish: "nonFoil"},
+	}
+
+	err := client.LookupCards(context.Background(), cards)
+
+	var notFound magic.CardsNotFoundError
+	if !errors.As(err, &notFound) {
+		t.Fatalf("lookup should report missing cards but got %v", err)
+	}
+	if diff := cmp.Diff([]string{"Not A Real Card"}, notFound.Names); diff != "" {
+		t.Errorf("wrong missing cards:\n%s", diff)
+	}
+
+	// Oracle text is too long to be worth comparing.
+	for i := range cards {
+		cards[i].OracleText = ""
+	}
+
+	want := []magic.DeckCard{
+		{
+			Board:           magic.BoardCommanders,
+			Quantity:        1,
+			Finish:          "nonFoil",
+			ScryfallID:      "4e439cd0-5ba1-45af-a868-f408e0a50465",
+			Name:            "Vial Smasher the Fierce",
+			Set:             "cmr",
+			CollectorNumber: "491",
+			Rarity:          "mythic",
+			ManaCost:        "{1}{B}{R}",
+			CMC:             3,
+			TypeLine:        "Legendary Creature — Goblin Berserker",
+			Colors:          magic.Rakdos,
+			ColorIdentity:   magic.Rakdos,
+		},
+		{
+			Board:           magic.BoardMainboard,
+			Quantity:        4,
+			Finish:          "foil",
+			ScryfallID:      "a3a0f3f4-2b3c-4d5e-9f60-718293a4b5c6",
+			Name:            "Delver of Secrets // Insectile Aberration",
+			Set:             "isd",
+			CollectorNumber: "51",
+			Rarity:          "common",
+			ManaCost:        "{U}",
+			CMC:             1,
+			TypeLine:        "Creature — Human Wizard // Creature — Human Insect",
+			Colors:          magic.MonoBlue,
+			ColorIdentity:   magic.MonoBlue,
+		},
+		{Board: magic.BoardMainboard, Quantity: 1, Name: "Not A Real Card", Finish: "nonFoil"},
+	}
+	if diff := cmp.Diff(want, cards); diff != "" {
+		t.Errorf("wrong card data:\n%s", diff)
+	}
+}
+
+func TestClientAPIError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	client := NewClient("", 0)
+	client.url = srv.URL
+
+	cards := []magic.DeckCard{{Quantity: 1, Name: "Sol Ring"}}
+	if err := client.LookupCards(context.Background(), cards); err == nil {
+		t.Error("looking up cards should fail when the api returns an error status")
+	}
+}
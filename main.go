@@ -1,11 +1,18 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
 	"time"
 
+	"github.com/jmoiron/sqlx"
 	"github.com/kelseyhightower/envconfig"
 
 	"github.com/jcbwlkr/deck-stats/internal/auth"
@@ -13,7 +20,10 @@ import (
 	"github.com/jcbwlkr/deck-stats/internal/domains/magic"
 	"github.com/jcbwlkr/deck-stats/internal/domains/users"
 	"github.com/jcbwlkr/deck-stats/internal/handlers"
+	"github.com/jcbwlkr/deck-stats/internal/services"
+	"github.com/jcbwlkr/deck-stats/internal/services/archidekt"
 	"github.com/jcbwlkr/deck-stats/internal/services/moxfield"
+	"github.com/jcbwlkr/deck-stats/internal/services/scryfall"
 )
 
 func main() {
@@ -36,7 +46,16 @@ func run() error {
 		DBUser string `envconfig:"db_user"`
 		DBPass string `envconfig:"db_pass"`
 
-		MoxfieldUserAgent string `envconfig:"moxfield_user_agent"`
+		MigrateOnStart bool `envconfig:"migrate_on_start"`
+
+		RefreshWorkers int `envconfig:"refresh_workers" default:"2"`
+
+		RatingInitial float64 `envconfig:"rating_initial" default:"1500"`
+		RatingK       float64 `envconfig:"rating_k" default:"32"`
+
+		MoxfieldUserAgent  string `envconfig:"moxfield_user_agent"`
+		ArchidektUserAgent string `envconfig:"archidekt_user_agent"`
+		ScryfallUserAgent  string `envconfig:"scryfall_user_agent"`
 	}
 	envconfig.MustProcess("", &config)
 
@@ -54,16 +73,175 @@ func run() error {
 		return err
 	}
 
+	ctx := context.Background()
+
+	ratings := magic.RatingConfig{
+		Initial: config.RatingInitial,
+		K:       config.RatingK,
+	}
+
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "migrate":
+			return migrate(ctx, db, os.Args[2:])
+		case "ratings":
+			return recomputeRatings(ctx, db, ratings, os.Args[2:])
+		default:
+			return fmt.Errorf("unknown command %q", os.Args[1])
+		}
+	}
+
+	if config.MigrateOnStart {
+		applied, err := database.MigrateUp(ctx, db)
+		if err != nil {
+			return err
+		}
+		for _, m := range applied {
+			slog.Info("applied migration", "version", m.Version, "name", m.Name)
+		}
+	}
+
 	mc := moxfield.NewClient(config.MoxfieldUserAgent, 1*time.Second)
+	ac := archidekt.NewClient(config.ArchidektUserAgent, 1*time.Second)
+	sc := scryfall.NewClient(config.ScryfallUserAgent, 100*time.Millisecond)
 	userService := users.NewService(db)
-	magicService := magic.NewService(db, userService, mc)
-	defer magicService.Wait()
+	sources := magic.Sources{
+		services.Moxfield:  mc,
+		services.Archidekt: ac,
+	}
+	magicService := magic.NewService(db, userService, sources, sc, ratings)
+	magicService.StartWorkers(config.RefreshWorkers)
+	magicService.StartLegalityRefresh()
 	authenticator := auth.NewAuthenticator(config.JWTSecret)
 
 	app := handlers.App(magicService, userService, authenticator)
 
-	// TODO(jlw) graceful shutdown
+	srv := http.Server{
+		Addr:    config.AddressServer,
+		Handler: app,
+	}
+
+	// Event streams never go idle so they have to be told to end for
+	// Shutdown to finish.
+	srv.RegisterOnShutdown(magicService.CloseRefreshSubscriptions)
+
+	serverErrors := make(chan error, 1)
+	go func() {
+		slog.Info("deck-stats api running", "address", config.AddressServer)
+		serverErrors <- srv.ListenAndServe()
+	}()
+
+	shutdown := make(chan os.Signal, 1)
+	signal.Notify(shutdown, syscall.SIGINT, syscall.SIGTERM)
+
+	select {
+	case err := <-serverErrors:
+		magicService.Shutdown()
+		return err
+
+	case sig := <-shutdown:
+		slog.Info("shutting down", "signal", sig)
+
+		// Stop taking requests first, then interrupt the refreshes already
+		// running so they are queued again for the next start.
+		ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		defer cancel()
+
+		err := srv.Shutdown(ctx)
+		magicService.Shutdown()
+		if err != nil {
+			return fmt.Errorf("could not stop server gracefully: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// migrate handles the `migrate up|down|status|baseline` commands.
+func migrate(ctx context.Context, db *sqlx.DB, args []string) error {
+	const usage = "usage: migrate up|down|status|baseline VERSION"
+	want := 1
+	if len(args) > 0 && args[0] == "baseline" {
+		want = 2
+	}
+	if len(args) != want {
+		return errors.New(usage)
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := database.MigrateUp(ctx, db)
+		for _, m := range applied {
+			fmt.Printf("applied %02d %s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Println("no pending migrations")
+		}
+
+	case "down":
+		m, ok, err := database.MigrateDown(ctx, db)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Println("no migrations to roll back")
+			return nil
+		}
+		fmt.Printf("rolled back %02d %s\n", m.Version, m.Name)
+
+	case "status":
+		status, err := database.GetMigrationStatus(ctx, db)
+		if err != nil {
+			return err
+		}
+		for _, st := range status {
+			applied := "pending"
+			if st.AppliedAt != nil {
+				applied = st.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%02d %-30s %s\n", st.Version, st.Name, applied)
+		}
+
+	case "baseline":
+		version, err := strconv.Atoi(args[1])
+		if err != nil {
+			return errors.New(usage)
+		}
+		marked, err := database.Baseline(ctx, db, version)
+		if err != nil {
+			return err
+		}
+		for _, m := range marked {
+			fmt.Printf("marked %02d %s as applied\n", m.Version, m.Name)
+		}
+		if len(marked) == 0 {
+			fmt.Println("no migrations to mark")
+		}
+
+	default:
+		return fmt.Errorf("unknown migrate command %q", args[0])
+	}
+
+	return nil
+}
+
+func recomputeRatings(ctx context.Context, db *sqlx.DB, ratings magic.RatingConfig, args []string) error {
+	if len(args) != 1 || args[0] != "recompute" {
+		return errors.New("usage: ratings recompute")
+	}
+
+	// Only the database is needed to rate games so the service has no deck
+	// sources or card lookup.
+	magicService := magic.NewService(db, users.NewService(db), nil, nil, ratings)
+
+	n, err := magicService.RecomputeAllRatings(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("recomputed ratings for %d users with initial %g and k %g\n", n, ratings.Initial, ratings.K)
 
-	slog.Info("deck-stats api running", "address", config.AddressServer)
-	return http.ListenAndServe(config.AddressServer, app)
+	return nil
 }